@@ -30,6 +30,17 @@ func (c CustomClaims) Validate(ctx context.Context) error {
 
 // EnsureValidToken is a middleware that will check the validity of our JWT.
 func EnsureValidToken(sugar *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	return newJWTMiddleware(sugar)
+}
+
+// OptionalValidToken is like EnsureValidToken, but lets requests without a
+// token through, unauthenticated. Requests with an invalid token are still
+// rejected.
+func OptionalValidToken(sugar *zap.SugaredLogger) func(next http.Handler) http.Handler {
+	return newJWTMiddleware(sugar, jwtmiddleware.WithCredentialsOptional(true))
+}
+
+func newJWTMiddleware(sugar *zap.SugaredLogger, opts ...jwtmiddleware.Option) func(next http.Handler) http.Handler {
 	issuerURL, err := url.Parse("https://" + os.Getenv("AUTH0_DOMAIN") + "/")
 	if err != nil {
 		sugar.Fatalf("failed to parse the issuer url: %v", err)
@@ -61,7 +72,7 @@ func EnsureValidToken(sugar *zap.SugaredLogger) func(next http.Handler) http.Han
 
 	middleware := jwtmiddleware.New(
 		jwtValidator.ValidateToken,
-		jwtmiddleware.WithErrorHandler(errorHandler),
+		append([]jwtmiddleware.Option{jwtmiddleware.WithErrorHandler(errorHandler)}, opts...)...,
 	)
 
 	return func(next http.Handler) http.Handler {
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidPreviewToken = errors.New("invalid or expired preview token")
+
+// PreviewTokens mints and verifies preview tokens, which let anyone holding
+// one read a single unpublished post until the token expires.
+//
+// A token is the expiry time and an HMAC-SHA256 of the post's slug and the
+// expiry time, so it can't be reused for another post nor extended.
+type PreviewTokens struct {
+	Secret []byte
+}
+
+// New returns a preview token for the post, valid until expiresAt.
+func (p *PreviewTokens) New(slug string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + base64.RawURLEncoding.EncodeToString(p.sign(slug, expiry))
+}
+
+// Verify checks that token is a preview token of the post that hasn't expired
+// at now.
+func (p *PreviewTokens) Verify(token string, slug string, now time.Time) error {
+	expiry, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidPreviewToken
+	}
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expiresAt {
+		return ErrInvalidPreviewToken
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, p.sign(slug, expiry)) {
+		return ErrInvalidPreviewToken
+	}
+
+	return nil
+}
+
+func (p *PreviewTokens) sign(slug string, expiry string) []byte {
+	mac := hmac.New(sha256.New, p.Secret)
+	mac.Write([]byte(slug))
+	mac.Write([]byte{0})
+	mac.Write([]byte(expiry))
+	return mac.Sum(nil)
+}
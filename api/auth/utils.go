@@ -7,9 +7,18 @@ import (
 	"github.com/auth0/go-jwt-middleware/v2/validator"
 )
 
+// Claims returns the validated claims of the request, if it is authenticated.
+func Claims(r *http.Request) (*validator.ValidatedClaims, bool) {
+	token, ok := r.Context().Value(jwtmiddleware.ContextKey{}).(*validator.ValidatedClaims)
+	return token, ok
+}
+
 // IsAdmin checks if the request is authenticated as an admin.
 func IsAdmin(r *http.Request) bool {
-	token := r.Context().Value(jwtmiddleware.ContextKey{}).(*validator.ValidatedClaims)
+	token, ok := Claims(r)
+	if !ok {
+		return false
+	}
 	claims := token.CustomClaims.(*CustomClaims)
 	return claims.HasScope("admin")
 }
@@ -8,6 +8,7 @@ import (
 	"hxann.com/blog/api/middleware"
 	"hxann.com/blog/api/resp"
 	"hxann.com/blog/models"
+	"hxann.com/blog/validation"
 )
 
 type Authors struct {
@@ -19,43 +20,24 @@ func (*Authors) AuthorGet(w http.ResponseWriter, r *http.Request) {
 
 	resp := NewAuthorResponse(author)
 
+	middleware.SetVersion(w, author.Version)
 	render.Render(w, r, resp)
 }
 
+// AuthorPut replaces the profile of the author. Fields that are left out are
+// emptied.
 func (a *Authors) AuthorPut(w http.ResponseWriter, r *http.Request) {
 	author := r.Context().Value(middleware.AuthorCtxKey{}).(*models.Author)
 
-	data := &AuthorRequest{}
-	if err := render.Bind(r, data); err != nil {
-		render.Render(w, r, resp.ErrBadRequest(err))
-		return
-	}
-
-	newAuthor := data.Author
-	if newAuthor == nil {
-		newAuthor = &models.Author{}
-	}
-	newAuthor.UserId = author.UserId
-	// Fill in missing fields
-	if newAuthor.FullName == "" {
-		newAuthor.FullName = author.FullName
-	}
-	if newAuthor.Email == "" {
-		newAuthor.Email = author.Email
-	}
-	if newAuthor.Bio == "" {
-		newAuthor.Bio = author.Bio
-	}
-
-	err := a.authors.Update(newAuthor)
-	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
-	}
+	a.putAuthor(w, r, author)
+}
 
-	resp := NewAuthorResponse(newAuthor)
+// AuthorPatch updates the profile of the author with a JSON Merge Patch of its
+// fields: null empties a field, and fields that are left out are kept.
+func (a *Authors) AuthorPatch(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.AuthorCtxKey{}).(*models.Author)
 
-	render.Render(w, r, resp)
+	a.patchAuthor(w, r, author)
 }
 
 func (a *Authors) AuthorsMeGet(w http.ResponseWriter, r *http.Request) {
@@ -63,42 +45,69 @@ func (a *Authors) AuthorsMeGet(w http.ResponseWriter, r *http.Request) {
 
 	resp := NewAuthorResponse(author)
 
+	middleware.SetVersion(w, author.Version)
 	render.Render(w, r, resp)
 }
 
+// AuthorsMePut replaces the profile of the requesting author, as AuthorPut.
 func (a *Authors) AuthorsMePut(w http.ResponseWriter, r *http.Request) {
 	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
 
+	a.putAuthor(w, r, author)
+}
+
+// AuthorsMePatch updates the profile of the requesting author, as AuthorPatch.
+func (a *Authors) AuthorsMePatch(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	a.patchAuthor(w, r, author)
+}
+
+func (a *Authors) putAuthor(w http.ResponseWriter, r *http.Request, author *models.Author) {
 	data := &AuthorRequest{}
 	if err := render.Bind(r, data); err != nil {
 		render.Render(w, r, resp.ErrBadRequest(err))
 		return
 	}
 
-	newAuthor := data.Author
-	if newAuthor == nil {
-		newAuthor = &models.Author{}
-	}
-	newAuthor.UserId = author.UserId
-	// Fill in missing fields
-	if newAuthor.FullName == "" {
-		newAuthor.FullName = author.FullName
+	a.replaceAuthor(w, r, author, data)
+}
+
+func (a *Authors) patchAuthor(w http.ResponseWriter, r *http.Request, author *models.Author) {
+	if !isMergePatch(r) {
+		render.Render(w, r, resp.ErrUnsupportedMediaType(errNotMergePatch))
+		return
 	}
-	if newAuthor.Email == "" {
-		newAuthor.Email = author.Email
+
+	authorCopy := *author
+	data := &AuthorRequest{}
+	if err := bindMergePatch(r, &AuthorRequest{Author: &authorCopy}, data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
 	}
-	if newAuthor.Bio == "" {
-		newAuthor.Bio = author.Bio
+
+	a.replaceAuthor(w, r, author, data)
+}
+
+// replaceAuthor replaces the profile of author with the one of the request.
+func (a *Authors) replaceAuthor(w http.ResponseWriter, r *http.Request, author *models.Author, data *AuthorRequest) {
+	version, ok := ifMatchVersion(r, author.Version)
+	if !ok {
+		middleware.RenderError(w, r, models.ErrStaleVersion)
+		return
 	}
 
-	err := a.authors.Update(newAuthor)
-	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+	newAuthor := data.Author
+	newAuthor.UserId = author.UserId
+
+	if err := a.authors.Update(newAuthor, version); err != nil {
+		middleware.RenderError(w, r, err)
+		return
 	}
 
 	resp := NewAuthorResponse(newAuthor)
 
+	middleware.SetVersion(w, newAuthor.Version)
 	render.Render(w, r, resp)
 }
 
@@ -110,8 +119,15 @@ func (ar *AuthorRequest) Bind(r *http.Request) error {
 	if ar == nil {
 		return errors.New("missing required Author fields")
 	}
+	if ar.Author == nil {
+		ar.Author = &models.Author{}
+	}
 
-	return nil
+	return validation.Validate(
+		validation.Field("full_name", ar.FullName, validation.Required, validation.MaxLength(maxFullNameLength)),
+		validation.Field("email", ar.Email, validation.Required, validation.MaxLength(maxEmailLength), validation.Email),
+		validation.Field("bio", ar.Bio, validation.MaxLength(maxBioLength)),
+	)
 }
 
 type AuthorResponse struct {
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+)
+
+const (
+	// coverFormField is the multipart form field holding the cover image.
+	coverFormField = "cover"
+	maxCoverSize   = 5 << 20
+)
+
+// coverExtensions maps the accepted cover content types to their extension.
+var coverExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// PostCoverUrlPut uploads the cover image of the cover form field to the blob
+// storage and makes it the cover of the post. The content type is sniffed
+// from the image rather than trusted from the request. Resized variants,
+// thumbnails and a placeholder are generated along with it. The image it
+// replaces, if it was uploaded, is deleted.
+func (p *Posts) PostCoverUrlPut(w http.ResponseWriter, r *http.Request) {
+	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+
+	image, _, errResp := readFormFile(r, coverFormField, maxCoverSize)
+	if errResp != nil {
+		render.Render(w, r, errResp)
+		return
+	}
+
+	contentType := http.DetectContentType(image)
+	ext, ok := coverExtensions[contentType]
+	if !ok {
+		render.Render(w, r, resp.ErrUnsupportedMediaType(fmt.Errorf("cover must be a JPEG, PNG, GIF or WebP image, not %s", contentType)))
+		return
+	}
+
+	key := "covers/" + post.Slug + "-" + uuid.New().String() + ext
+	coverUrl, err := p.blobs.Put(r.Context(), key, bytes.NewReader(image), contentType)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	derivatives, err := deriveImage(r.Context(), p.blobs, key, image)
+	if err != nil {
+		deleteUpload(r, p.blobs, key, nil)
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	previous, err := p.posts.SetCover(post.Slug, coverUrl, key, derivatives)
+	if err != nil {
+		deleteUpload(r, p.blobs, key, derivatives)
+		middleware.RenderError(w, r, err)
+		return
+	}
+	if previous != nil {
+		if err := imaging.Delete(r.Context(), p.blobs, previous.Key, previous.Derivatives); err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
+	}
+	post.CoverUrl = &coverUrl
+	post.CoverImages = derivatives
+
+	postResp, err := p.NewPostResponse(r.Context(), post, models.AllPostFields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, postResp)
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/constants"
+	"hxann.com/blog/feed"
+	"hxann.com/blog/markdown"
+	"hxann.com/blog/models"
+)
+
+// feedPostFields are the fields of the posts of feeds.
+var feedPostFields = models.AllPostFields.Without("last_post_slug", "next_post_slug")
+
+// Site describes the website where posts are read, which feeds and sitemaps
+// link to.
+type Site struct {
+	Title       string
+	Description string
+	// URL is the root of the website, without a trailing slash. Posts are
+	// at URL/posts/{slug}, pages at URL/pages/{slug}, tags at URL/tags/{tag}
+	// and authors at URL/authors/{user_id}.
+	URL string
+}
+
+type Feeds struct {
+	posts      *models.PostModel
+	renderings *markdown.Cache
+	site       Site
+	// size is the number of posts of a feed.
+	size int
+}
+
+// RSSGet serves the feed of the latest published posts as RSS 2.0. Under
+// /tags/{tag} and /authors/{user_id}, only the posts of the tag or author
+// are in the feed.
+func (f *Feeds) RSSGet(w http.ResponseWriter, r *http.Request) {
+	f.serveFeed(w, r, feed.RSSContentType, feed.WriteRSS)
+}
+
+// AtomGet serves the feed as RSSGet does, as Atom.
+func (f *Feeds) AtomGet(w http.ResponseWriter, r *http.Request) {
+	f.serveFeed(w, r, feed.AtomContentType, feed.WriteAtom)
+}
+
+// JSONGet serves the feed as RSSGet does, as JSON Feed.
+func (f *Feeds) JSONGet(w http.ResponseWriter, r *http.Request) {
+	f.serveFeed(w, r, feed.JSONContentType, feed.WriteJSON)
+}
+
+// serveFeed writes the feed of the request. Feed readers poll, so the feed is
+// only sent when it changed since they last fetched it, according to its
+// ETag or Last-Modified headers.
+func (f *Feeds) serveFeed(w http.ResponseWriter, r *http.Request, contentType string, write func(io.Writer, *feed.Feed) error) {
+	fd, err := f.feedOf(r)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := write(&body, fd); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	serveDocument(w, r, contentType, fd.Updated, body.Bytes())
+}
+
+// feedOf returns the feed of the latest posts of the tag or author in the
+// request context, or of every post.
+func (f *Feeds) feedOf(r *http.Request) (*feed.Feed, error) {
+	base := requestBaseURL(r)
+	fd := &feed.Feed{
+		Title:       f.site.Title,
+		Description: f.site.Description,
+		Link:        f.site.URL,
+		FeedURL:     base + r.URL.Path,
+	}
+
+	var filter models.PostFilter
+	if tag, ok := r.Context().Value(middleware.TagCtxKey{}).(*models.Tag); ok {
+		filter.Tag = tag.Slug
+		fd.Title += " - " + tag.Name
+		fd.Link += "/tags/" + url.PathEscape(tag.Slug)
+	}
+	if author, ok := r.Context().Value(middleware.AuthorCtxKey{}).(*models.Author); ok {
+		filter.Author = author.UserId
+		fd.Title += " - " + author.FullName
+		fd.Link += "/authors/" + url.PathEscape(author.UserId)
+	}
+
+	opts := models.PageOptions{Page: 1, PageSize: f.size, Sort: defaultPostSort}
+	posts, _, err := f.posts.Page(opts, filter, feedPostFields)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, post := range posts {
+		doc := f.renderings.Render(r.Context(), "posts:"+post.Slug+":"+post.ModifiedAt, post.Content)
+
+		link := f.site.URL + "/posts/" + url.PathEscape(post.Slug)
+		item := &feed.Item{
+			Id:          link,
+			Title:       post.Title,
+			Link:        link,
+			Summary:     post.Excerpt,
+			ContentHTML: doc.HTML,
+			Tags:        post.Tags,
+			Published:   parseLocalTime(post.PublishedAt),
+			Updated:     parseLocalTime(post.ModifiedAt),
+		}
+		if post.Author != nil {
+			item.Authors = append(item.Authors, post.Author.FullName)
+		}
+		for _, coAuthor := range post.Co_Authors {
+			item.Authors = append(item.Authors, coAuthor.FullName)
+		}
+		if post.CoverUrl != nil {
+			item.Image = &feed.Enclosure{URL: absoluteURL(base, *post.CoverUrl), Type: imageType(*post.CoverUrl)}
+		}
+
+		if item.Updated.After(fd.Updated) {
+			fd.Updated = item.Updated
+		}
+		if item.Published.After(fd.Updated) {
+			fd.Updated = item.Published
+		}
+		fd.Items = append(fd.Items, item)
+	}
+
+	return fd, nil
+}
+
+// serveDocument writes a document that clients fetch periodically, such as a
+// feed or a sitemap. It is only sent when it changed since they last fetched
+// it, according to its ETag or its modification time.
+func serveDocument(w http.ResponseWriter, r *http.Request, contentType string, modified time.Time, body []byte) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	middleware.WriteConditional(w, r, body)
+}
+
+// requestBaseURL returns the scheme and host the request was sent to, taking
+// the proxy in front of the app into account.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// absoluteURL resolves URLs relative to the API, such as the ones of local
+// uploads, against base.
+func absoluteURL(base string, u string) string {
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+		return base + u
+	}
+	return u
+}
+
+// imageType guesses the content type of an image from its URL.
+func imageType(u string) string {
+	if parsed, err := url.Parse(u); err == nil {
+		if contentType := mime.TypeByExtension(path.Ext(parsed.Path)); strings.HasPrefix(contentType, "image/") {
+			return contentType
+		}
+	}
+	return "image/jpeg"
+}
+
+// parseLocalTime parses a datetime of the database, which is in local time.
+func parseLocalTime(s string) time.Time {
+	t, err := time.ParseInLocation(constants.PublishedAtFormat, s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func NewFeeds(posts *models.PostModel, renderings *markdown.Cache, site Site, size int) *Feeds {
+	return &Feeds{
+		posts:      posts,
+		renderings: renderings,
+		site:       site,
+		size:       size,
+	}
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"hxann.com/blog/api/auth"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+	"hxann.com/blog/storage"
+)
+
+const (
+	// mediaFormField is the multipart form field holding the uploaded file.
+	mediaFormField = "file"
+	maxMediaSize   = 20 << 20
+	// maxFilenameLength is the length of the filename column.
+	maxFilenameLength = 255
+)
+
+// mediaExtensions maps the accepted media content types to their extension.
+var mediaExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// defaultMediaSort is the order of media when the request doesn't specify one.
+var defaultMediaSort = models.Sort{Field: "createdAt", Desc: true}
+
+type Media struct {
+	media *models.MediaModel
+	blobs storage.Storage
+}
+
+// MediaGet lists the media uploaded by the requesting author, or every media
+// for admins.
+func (md *Media) MediaGet(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	opts, err := parsePageOptions(r, defaultMediaSort, models.ParseMediaSort)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	ownerUserId := author.UserId
+	if auth.IsAdmin(r) {
+		ownerUserId = ""
+	}
+
+	list, total, err := md.media.Page(*opts, ownerUserId)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	setPaginationHeaders(w, r, opts, total)
+	render.RenderList(w, r, NewMediaListResponse(list))
+}
+
+// MediaPost uploads the file of the file form field to the blob storage. The
+// content type is sniffed from the file rather than trusted from the request.
+func (md *Media) MediaPost(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	content, filename, errResp := readFormFile(r, mediaFormField, maxMediaSize)
+	if errResp != nil {
+		render.Render(w, r, errResp)
+		return
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(content))
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+	ext, ok := mediaExtensions[contentType]
+	if !ok {
+		render.Render(w, r, resp.ErrUnsupportedMediaType(fmt.Errorf("unsupported file type %s", contentType)))
+		return
+	}
+
+	media := &models.Media{
+		StorageKey:   "media/" + uuid.New().String() + ext,
+		Filename:     cleanFilename(filename, ext),
+		ContentType:  contentType,
+		Size:         int64(len(content)),
+		AuthorUserId: author.UserId,
+	}
+	media.Url, err = md.blobs.Put(r.Context(), media.StorageKey, bytes.NewReader(content), contentType)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		media.Derivatives, err = deriveImage(r.Context(), md.blobs, media.StorageKey, content)
+		if err != nil {
+			deleteUpload(r, md.blobs, media.StorageKey, nil)
+			middleware.RenderError(w, r, err)
+			return
+		}
+	}
+
+	if err := md.media.Add(media); err != nil {
+		deleteUpload(r, md.blobs, media.StorageKey, media.Derivatives)
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewMediaResponse(media))
+}
+
+func (md *Media) MediaItemGet(w http.ResponseWriter, r *http.Request) {
+	media := r.Context().Value(middleware.MediaCtxKey{}).(*models.Media)
+
+	render.Render(w, r, NewMediaResponse(media))
+}
+
+// MediaItemDelete deletes the media, unless a published post still uses it.
+func (md *Media) MediaItemDelete(w http.ResponseWriter, r *http.Request) {
+	media := r.Context().Value(middleware.MediaCtxKey{}).(*models.Media)
+
+	if err := md.media.Delete(media.Id); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	// The file is deleted last, so that a failure leaves an orphan file rather
+	// than a media without its file.
+	if err := imaging.Delete(r.Context(), md.blobs, media.StorageKey, media.Derivatives); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// cleanFilename returns the base name of the uploaded file, or a name made of
+// ext if there is none.
+func cleanFilename(filename string, ext string) string {
+	filename = path.Base(strings.ReplaceAll(filename, `\`, "/"))
+	if filename == "." || filename == "/" {
+		filename = "file" + ext
+	}
+	if len(filename) > maxFilenameLength {
+		filename = strings.ToValidUTF8(filename[len(filename)-maxFilenameLength:], "")
+	}
+	return filename
+}
+
+type MediaResponse struct {
+	*models.Media
+}
+
+func (resp *MediaResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewMediaResponse(media *models.Media) *MediaResponse {
+	return &MediaResponse{Media: media}
+}
+
+func NewMediaListResponse(list []*models.Media) []render.Renderer {
+	renderers := []render.Renderer{}
+	for _, media := range list {
+		renderers = append(renderers, NewMediaResponse(media))
+	}
+	return renderers
+}
+
+func NewMedia(media *models.MediaModel, blobs storage.Storage) *Media {
+	return &Media{
+		media: media,
+		blobs: blobs,
+	}
+}
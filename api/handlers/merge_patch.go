@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"github.com/go-chi/render"
+)
+
+// mergePatchContentType is the media type of JSON Merge Patch documents.
+// application/json is accepted as well.
+const mergePatchContentType = "application/merge-patch+json"
+
+var (
+	errNotMergePatch   = errors.New("patch must be application/merge-patch+json")
+	errMergePatchShape = errors.New("patch must be a JSON object")
+)
+
+// isMergePatch reports whether the body of the request is a JSON Merge Patch.
+func isMergePatch(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && (mediaType == mergePatchContentType || mediaType == "application/json")
+}
+
+// bindMergePatch applies the JSON Merge Patch of the request body to current,
+// the editable fields of a resource, and binds the result to v as if it were
+// the body of a full replacement.
+func bindMergePatch(r *http.Request, current interface{}, v render.Binder) error {
+	var patch interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		return err
+	}
+	// A patch that isn't an object replaces the whole resource, which can't
+	// be valid.
+	if _, ok := patch.(map[string]interface{}); !ok {
+		return errMergePatchShape
+	}
+
+	b, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var target interface{}
+	if err := json.Unmarshal(b, &target); err != nil {
+		return err
+	}
+
+	b, err = json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	return v.Bind(r)
+}
+
+// mergePatch applies patch to target as described by RFC 7396: null deletes a
+// member, objects are merged recursively, and anything else replaces the
+// target.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
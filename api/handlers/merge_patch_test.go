@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"hxann.com/blog/models"
+	"hxann.com/blog/validation"
+)
+
+// The examples of RFC 7396, appendix A.
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	decode := func(s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	for _, test := range tests {
+		got := mergePatch(decode(test.target), decode(test.patch))
+		if !reflect.DeepEqual(got, decode(test.result)) {
+			t.Errorf("mergePatch(%s, %s) = %v, want %s", test.target, test.patch, got, test.result)
+		}
+	}
+}
+
+func TestIsMergePatch(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/merge-patch+json", true},
+		{"application/merge-patch+json; charset=utf-8", true},
+		{"application/json", true},
+		{"application/json-patch+json", false},
+		{"text/plain", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPatch, "/", nil)
+		r.Header.Set("Content-Type", test.contentType)
+		if got := isMergePatch(r); got != test.want {
+			t.Errorf("isMergePatch(%q) = %t, want %t", test.contentType, got, test.want)
+		}
+	}
+}
+
+func patchRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", mergePatchContentType)
+	return r
+}
+
+func testPost() *models.Post {
+	coverUrl := "https://example.com/cover.png"
+	return &models.Post{
+		Slug:        "post",
+		Title:       "Title",
+		Excerpt:     "Excerpt",
+		Content:     "Content",
+		Published:   true,
+		PublishedAt: "2024-05-01 12:00:00",
+		Author:      &models.Author{UserId: "auth0|1"},
+		Co_Authors:  []*models.Author{{UserId: "auth0|2"}},
+		CoverUrl:    &coverUrl,
+		Tags:        []string{"go"},
+		Version:     3,
+	}
+}
+
+func TestBindMergePatchPost(t *testing.T) {
+	data := &PostRequest{}
+	err := bindMergePatch(patchRequest(`{"title":"New title","cover_url":null,"tags":["go","go","web"]}`), newPostRequest(testPost()), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := data.Post
+	if post.Title != "New title" {
+		t.Errorf("got title %q, want the patched one", post.Title)
+	}
+	if post.Excerpt != "Excerpt" || post.Content != "Content" || !post.Published || post.PublishedAt != "2024-05-01 12:00:00" {
+		t.Errorf("got %+v, want the fields left out kept", post)
+	}
+	if post.CoverUrl != nil {
+		t.Errorf("got cover_url %q, want it emptied", *post.CoverUrl)
+	}
+	if !reflect.DeepEqual(post.Tags, []string{"go", "web"}) {
+		t.Errorf("got tags %v, want them replaced and deduplicated", post.Tags)
+	}
+	// Only renames when the patch sets a slug
+	if post.Slug != "" {
+		t.Errorf("got slug %q, want none", post.Slug)
+	}
+	if data.Author != "auth0|1" || !reflect.DeepEqual(data.Co_Authors, []string{"auth0|2"}) {
+		t.Errorf("got authors %q and %v, want them kept", data.Author, data.Co_Authors)
+	}
+}
+
+func TestBindMergePatchPostUnpublishes(t *testing.T) {
+	data := &PostRequest{}
+	if err := bindMergePatch(patchRequest(`{"published":false,"slug":"renamed"}`), newPostRequest(testPost()), data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Post.Published {
+		t.Error("got a published post, want it unpublished")
+	}
+	if data.Post.Slug != "renamed" {
+		t.Errorf("got slug %q, want renamed", data.Post.Slug)
+	}
+}
+
+func TestBindMergePatchErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  error
+	}{
+		{"not an object", `["title"]`, errMergePatchShape},
+		{"null", `null`, errMergePatchShape},
+		{"required field emptied", `{"title":null}`, validation.Errors{}},
+		{"invalid field", `{"slug":"Not A Slug"}`, validation.Errors{}},
+		{"wrong type", `{"tags":"go"}`, &json.UnmarshalTypeError{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := bindMergePatch(patchRequest(test.patch), newPostRequest(testPost()), &PostRequest{})
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			if test.want == errMergePatchShape {
+				if err != errMergePatchShape {
+					t.Errorf("got %v, want %v", err, errMergePatchShape)
+				}
+				return
+			}
+			if target := reflect.New(reflect.TypeOf(test.want)); !errors.As(err, target.Interface()) {
+				t.Errorf("got %T %v, want a %T", err, err, test.want)
+			}
+		})
+	}
+
+	if err := bindMergePatch(patchRequest(`{"title":`), newPostRequest(testPost()), &PostRequest{}); err == nil {
+		t.Error("got no error for invalid JSON")
+	}
+}
+
+func TestBindMergePatchAuthor(t *testing.T) {
+	author := &models.Author{UserId: "auth0|1", FullName: "Ann", Email: "ann@example.com", Bio: "Bio", Version: 2}
+
+	data := &AuthorRequest{}
+	if err := bindMergePatch(patchRequest(`{"bio":null,"full_name":"Ann B."}`), &AuthorRequest{Author: author}, data); err != nil {
+		t.Fatal(err)
+	}
+	if data.FullName != "Ann B." || data.Email != "ann@example.com" || data.Bio != "" {
+		t.Errorf("got %+v, want the name changed and the bio emptied", data.Author)
+	}
+	if author.Bio != "Bio" {
+		t.Error("the current author was modified")
+	}
+}
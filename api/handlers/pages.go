@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/auth"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/constants"
+	"hxann.com/blog/models"
+	"hxann.com/blog/validation"
+)
+
+type Pages struct {
+	pages   *models.PageModel
+	authors *models.AuthorModel
+}
+
+// defaultPageSort is the order of pages when the request doesn't specify one,
+// i.e. the menu order.
+var defaultPageSort = models.Sort{Field: "menuOrder"}
+
+func (pg *Pages) PagesGet(w http.ResponseWriter, r *http.Request) {
+	sort := defaultPageSort
+	if s := r.URL.Query().Get("sort"); s != "" {
+		var err error
+		sort, err = models.ParsePageSort(s)
+		if err != nil {
+			render.Render(w, r, resp.ErrBadRequest(err))
+			return
+		}
+	}
+
+	pages, err := pg.pages.All(sort, pageFilterOf(r))
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.RenderList(w, r, NewPageListResponse(pages))
+}
+
+func (pg *Pages) PagesPost(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	data := &PageRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	page := data.Page
+
+	authorIds := append(data.Co_Authors, author.UserId)
+	authors, err := authorIdsToAuthors(pg.authors, authorIds)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+	for _, dbAuthor := range authors {
+		if dbAuthor.UserId == author.UserId {
+			page.Author = dbAuthor
+		} else {
+			page.Co_Authors = append(page.Co_Authors, dbAuthor)
+		}
+	}
+
+	if err := pg.pages.Add(page); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	insertedPage, err := pg.pages.Get(page.Slug)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewPageResponse(insertedPage))
+}
+
+func (pg *Pages) PageGet(w http.ResponseWriter, r *http.Request) {
+	page := r.Context().Value(middleware.PageCtxKey{}).(*models.Page)
+
+	render.Render(w, r, NewPageResponse(page))
+}
+
+func (pg *Pages) PagePut(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	page := r.Context().Value(middleware.PageCtxKey{}).(*models.Page)
+
+	data := &PageRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	newPage := data.Page
+	if newPage == nil {
+		newPage = &models.Page{}
+	}
+	// Provides the slug from context
+	newPage.Slug = page.Slug
+	// Fill in missing fields
+	if newPage.Title == "" {
+		newPage.Title = page.Title
+	}
+	if newPage.Excerpt == "" {
+		newPage.Excerpt = page.Excerpt
+	}
+	if newPage.Content == "" {
+		newPage.Content = page.Content
+	}
+	if data.MenuOrder == nil {
+		newPage.MenuOrder = page.MenuOrder
+	}
+	if data.Published == nil {
+		newPage.Published = page.Published
+	}
+	if newPage.PublishedAt == "" {
+		newPage.PublishedAt = page.PublishedAt
+	}
+
+	// if not the original author or blog's admin, they can't change authors
+	if !auth.IsAdmin(r) && author.UserId != page.Author.UserId && (data.Author != "" || data.Co_Authors != nil) {
+		middleware.RenderError(w, r, models.Forbidden("you must be the original author in order to change authors"))
+		return
+	}
+
+	if data.Author == "" {
+		newPage.Author = page.Author
+	} else {
+		// The original author is making another author the original author.
+		newOriginalAuthor, err := pg.authors.Get(data.Author)
+		if err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
+		newPage.Author = newOriginalAuthor
+	}
+
+	if data.Co_Authors == nil {
+		newPage.Co_Authors = page.Co_Authors
+	} else {
+		authors, err := authorIdsToAuthors(pg.authors, data.Co_Authors)
+		if err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
+
+		newPage.Co_Authors = authors
+	}
+
+	if err := pg.pages.Update(newPage); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewPageResponse(newPage))
+}
+
+func (pg *Pages) PageDelete(w http.ResponseWriter, r *http.Request) {
+	page := r.Context().Value(middleware.PageCtxKey{}).(*models.Page)
+
+	if err := pg.pages.Delete(page.Slug); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type PageRequest struct {
+	*models.Page
+	MenuOrder  *int     `json:"menu_order"`
+	Published  *bool    `json:"published"`
+	Author     string   `json:"author"`
+	Co_Authors []string `json:"co_authors"`
+}
+
+func (pr *PageRequest) Bind(r *http.Request) error {
+	if pr == nil {
+		return errors.New("missing required Page fields")
+	}
+	if pr.Page == nil {
+		pr.Page = &models.Page{}
+	}
+
+	if err := pr.validate(r.Method == http.MethodPost); err != nil {
+		return err
+	}
+
+	if pr.MenuOrder != nil {
+		pr.Page.MenuOrder = *pr.MenuOrder
+	}
+	if pr.Published != nil {
+		pr.Page.Published = *pr.Published
+	}
+
+	return nil
+}
+
+// validate checks the fields of the request. New pages need all of their
+// content, while replacements keep the fields they leave out, and the slug.
+func (pr *PageRequest) validate(creating bool) error {
+	page := pr.Page
+	var checks []validation.Check
+	if creating {
+		checks = append(checks, validation.Field("slug", page.Slug, validation.Required, validation.MaxLength(maxSlugLength), validation.Slug))
+	}
+	checks = append(checks,
+		validation.Field("title", page.Title, validation.RequiredIf(creating), validation.MaxLength(maxTitleLength)),
+		validation.Field("excerpt", page.Excerpt, validation.RequiredIf(creating), validation.MaxLength(maxExcerptLength)),
+		validation.Field("content", page.Content, validation.RequiredIf(creating), validation.MaxBytes(maxContentBytes)),
+		validation.Field("published_at", page.PublishedAt, validation.Time(constants.PublishedAtFormat)),
+		validation.Field("author", pr.Author, validation.MaxLength(maxUserIdLength)),
+		validation.Each("co_authors", pr.Co_Authors, validation.Required, validation.MaxLength(maxUserIdLength)),
+	)
+	return validation.Validate(checks...)
+}
+
+type PageResponse struct {
+	*models.Page
+	Co_Authors []*AuthorResponse `json:"co_authors"`
+}
+
+func (resp *PageResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewPageResponse(page *models.Page) *PageResponse {
+	return &PageResponse{
+		Page:       page,
+		Co_Authors: NewAuthorListResponse(page.Co_Authors),
+	}
+}
+
+func NewPageListResponse(pages []*models.Page) []render.Renderer {
+	list := []render.Renderer{}
+	for _, page := range pages {
+		list = append(list, NewPageResponse(page))
+	}
+	return list
+}
+
+// pageFilterOf returns the filter of the pages visible to the request. Drafts
+// and scheduled pages are only visible to their authors and admins.
+func pageFilterOf(r *http.Request) models.PageFilter {
+	filter := models.PageFilter{ViewerIsAdmin: auth.IsAdmin(r)}
+	if author, ok := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author); ok {
+		filter.ViewerUserId = author.UserId
+	}
+	return filter
+}
+
+func NewPages(pages *models.PageModel, authors *models.AuthorModel) *Pages {
+	return &Pages{
+		pages:   pages,
+		authors: authors,
+	}
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"hxann.com/blog/models"
+)
+
+const (
+	defaultPageSize = 10
+	maxPageSize     = 100
+
+	totalCountHeader = "X-Total-Count"
+)
+
+// parsePageOptions reads the page, pageSize and sort query parameters of the
+// request. parseSort validates the sort field against the listed resource.
+func parsePageOptions(r *http.Request, defaultSort models.Sort, parseSort func(string) (models.Sort, error)) (*models.PageOptions, error) {
+	query := r.URL.Query()
+	opts := &models.PageOptions{
+		Page:     1,
+		PageSize: defaultPageSize,
+		Sort:     defaultSort,
+	}
+
+	if page := query.Get("page"); page != "" {
+		n, err := strconv.Atoi(page)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("page must be a positive integer")
+		}
+		opts.Page = n
+	}
+
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		n, err := strconv.Atoi(pageSize)
+		if err != nil || n < 1 || n > maxPageSize {
+			return nil, fmt.Errorf("pageSize must be an integer between 1 and %d", maxPageSize)
+		}
+		opts.PageSize = n
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		s, err := parseSort(sort)
+		if err != nil {
+			return nil, err
+		}
+		opts.Sort = s
+	}
+
+	return opts, nil
+}
+
+// setPaginationHeaders sets the X-Total-Count header and a Link header
+// containing the first, last, prev and next pages of the request.
+func setPaginationHeaders(w http.ResponseWriter, r *http.Request, opts *models.PageOptions, total int) {
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+
+	lastPage := (total + opts.PageSize - 1) / opts.PageSize
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{
+		pageLink(r, 1, "first"),
+		pageLink(r, lastPage, "last"),
+	}
+	if opts.Page > 1 {
+		prev := opts.Page - 1
+		if prev > lastPage {
+			prev = lastPage
+		}
+		links = append(links, pageLink(r, prev, "prev"))
+	}
+	if opts.Page < lastPage {
+		links = append(links, pageLink(r, opts.Page+1, "next"))
+	}
+
+	w.Header().Set("Link", strings.Join(links, ", "))
+}
+
+func pageLink(r *http.Request, page int, rel string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("page", strconv.Itoa(page))
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel)
+}
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// postCursor is the JSON form of models.PostCursor. Clients only ever see it
+// base64-encoded, so its format may change without notice.
+type postCursor struct {
+	PublishedAt string `json:"p"`
+	Slug        string `json:"s"`
+}
+
+func encodePostCursor(cursor *models.PostCursor) string {
+	b, _ := json.Marshal(postCursor{PublishedAt: cursor.PublishedAt, Slug: cursor.Slug})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodePostCursor decodes a cursor made by encodePostCursor. An empty string
+// decodes to nil, meaning the first page.
+func decodePostCursor(s string) (*models.PostCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c postCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.PublishedAt == "" || c.Slug == "" {
+		return nil, errInvalidCursor
+	}
+
+	return &models.PostCursor{PublishedAt: c.PublishedAt, Slug: c.Slug}, nil
+}
+
+// cursorLink returns a Link header value pointing at the page after cursor.
+func cursorLink(r *http.Request, cursor string) string {
+	u := *r.URL
+	query := u.Query()
+	query.Set("cursor", cursor)
+	u.RawQuery = query.Encode()
+	return fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI())
+}
@@ -1,43 +1,125 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-chi/render"
-	"github.com/go-sql-driver/mysql"
 	"hxann.com/blog/api/auth"
 	"hxann.com/blog/api/middleware"
 	"hxann.com/blog/api/resp"
 	"hxann.com/blog/constants"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/markdown"
 	"hxann.com/blog/models"
+	"hxann.com/blog/storage"
+	"hxann.com/blog/validation"
 )
 
 type Posts struct {
-	posts   *models.PostModel
-	authors *models.AuthorModel
+	posts         *models.PostModel
+	authors       *models.AuthorModel
+	previewTokens *auth.PreviewTokens
+	blobs         storage.Storage
+	renderings    *markdown.Cache
 }
 
+// defaultPostSort is the order of posts when the request doesn't specify one.
+var defaultPostSort = models.Sort{Field: "publishedAt", Desc: true}
+
 func (p *Posts) PostsGet(w http.ResponseWriter, r *http.Request) {
-	// Fetch posts from db
-	modelPosts, err := p.posts.All()
+	if _, ok := r.URL.Query()["cursor"]; ok {
+		p.postsGetByCursor(w, r)
+		return
+	}
+
+	opts, err := parsePageOptions(r, defaultPostSort, models.ParsePostSort)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
 
+	fields, err := parsePostFields(r, models.PostSummaryFields)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
 	}
 
-	postsResp, err := p.NewPostListResponse(modelPosts)
+	// Fetch posts from db
+	modelPosts, total, err := p.posts.Page(*opts, postFilterOf(r), fields)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
+	postsResp, err := p.NewPostListResponse(r.Context(), modelPosts, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	setPaginationHeaders(w, r, opts, total)
 	render.RenderList(w, r, postsResp)
 }
 
+// postsGetByCursor lists published posts, newest first, using keyset
+// pagination. An empty cursor query parameter requests the first page.
+func (p *Posts) postsGetByCursor(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("page") != "" || query.Get("sort") != "" {
+		render.Render(w, r, resp.ErrBadRequest(errors.New("cursor can't be combined with page or sort")))
+		return
+	}
+
+	cursor, err := decodePostCursor(query.Get("cursor"))
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	opts, err := parsePageOptions(r, defaultPostSort, models.ParsePostSort)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	fields, err := parsePostFields(r, models.PostSummaryFields)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	// Fetch one more post to know whether there is a next page
+	modelPosts, err := p.posts.After(cursor, opts.PageSize+1, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	pageResp := &PostCursorPageResponse{}
+	if len(modelPosts) > opts.PageSize {
+		modelPosts = modelPosts[:opts.PageSize]
+		last := modelPosts[len(modelPosts)-1]
+		nextCursor := encodePostCursor(&models.PostCursor{PublishedAt: last.PublishedAt, Slug: last.Slug})
+		pageResp.NextCursor = &nextCursor
+		w.Header().Set("Link", cursorLink(r, nextCursor))
+	}
+
+	pageResp.Posts, err = p.NewPostListResponse(r.Context(), modelPosts, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, pageResp)
+}
+
 func (p *Posts) PostsPost(w http.ResponseWriter, r *http.Request) {
 	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
 
@@ -49,22 +131,11 @@ func (p *Posts) PostsPost(w http.ResponseWriter, r *http.Request) {
 
 	post := data.Post
 
-	// Check required fields
-	if post.Slug == "" || post.Title == "" || post.Excerpt == "" ||
-		post.Content == "" {
-		render.Render(w, r, resp.ErrBadRequest(errors.New("some of the required fields are not present. Required fields: slug, title, excerpt, content")))
-		return
-	}
-
 	authorIds := append(data.Co_Authors, author.UserId)
-	authors, missingAuthorId, err := p.AuthorIdsToAuthors(authorIds)
+	authors, err := p.AuthorIdsToAuthors(authorIds)
 	if err != nil {
-		if missingAuthorId != nil {
-			render.Render(w, r, resp.ErrNotFoundCustom(fmt.Errorf("couldn't find author with user_id of %s", *missingAuthorId)))
-			return
-		}
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 	for _, dbAuthor := range authors {
 		if dbAuthor.UserId == author.UserId {
@@ -75,26 +146,20 @@ func (p *Posts) PostsPost(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := p.posts.Add(post); err != nil {
-		if driverErr, ok := err.(*mysql.MySQLError); ok {
-			if driverErr.Number == 1062 {
-				render.Render(w, r, resp.ErrDuplicate(err))
-				return
-			}
-		}
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
 	insertedPost, err := p.posts.Get(post.Slug)
 	if err != nil {
-		w.WriteHeader(http.StatusCreated)
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
-	resp, err := p.NewPostResponse(insertedPost)
+	resp, err := p.NewPostResponse(r.Context(), insertedPost, models.AllPostFields)
 	if err != nil {
-		w.WriteHeader(http.StatusCreated)
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
 	render.Status(r, http.StatusCreated)
@@ -104,18 +169,32 @@ func (p *Posts) PostsPost(w http.ResponseWriter, r *http.Request) {
 func (p *Posts) PostGet(w http.ResponseWriter, r *http.Request) {
 	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
 
-	postResp, err := p.NewPostResponse(post)
+	// PostContext has already rejected invalid fields
+	fields, _ := parsePostFields(r, models.AllPostFields)
+
+	switch r.URL.Query().Get("format") {
+	case "", "markdown":
+	case "html":
+		fields = fields.With(models.HTMLPostFields...)
+	default:
+		render.Render(w, r, resp.ErrBadRequest(errors.New("format must be markdown or html")))
+		return
+	}
+
+	postResp, err := p.NewPostResponse(r.Context(), post, fields)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
+	middleware.SetVersion(w, post.Version)
 	render.Render(w, r, postResp)
 }
 
+// PostPut replaces the post. Fields that are left out are emptied, but for
+// the original author, which is kept, and published_at, which is kept or set
+// to now when the post is published.
 func (p *Posts) PostPut(w http.ResponseWriter, r *http.Request) {
-	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
-
 	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
 
 	data := &PostRequest{}
@@ -124,85 +203,141 @@ func (p *Posts) PostPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newPost := data.Post
-	if newPost == nil {
-		newPost = &models.Post{}
+	p.replacePost(w, r, post, data)
+}
+
+// PostPatch updates the post with a JSON Merge Patch of its fields: null
+// empties a field, and fields that are left out are kept.
+func (p *Posts) PostPatch(w http.ResponseWriter, r *http.Request) {
+	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+
+	if !isMergePatch(r) {
+		render.Render(w, r, resp.ErrUnsupportedMediaType(errNotMergePatch))
+		return
 	}
-	// Provides the slug from context
-	newPost.Slug = post.Slug
-	// Fill in missing fields
-	if newPost.Title == "" {
-		newPost.Title = post.Title
+
+	data := &PostRequest{}
+	if err := bindMergePatch(r, newPostRequest(post), data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
 	}
-	if newPost.Excerpt == "" {
-		newPost.Excerpt = post.Excerpt
+
+	p.replacePost(w, r, post, data)
+}
+
+// replacePost replaces post with the one of the request.
+func (p *Posts) replacePost(w http.ResponseWriter, r *http.Request, post *models.Post, data *PostRequest) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	version, ok := ifMatchVersion(r, post.Version)
+	if !ok {
+		middleware.RenderError(w, r, models.ErrStaleVersion)
+		return
 	}
-	if newPost.Content == "" {
-		newPost.Content = post.Content
+
+	newPost := data.Post
+	// Keeps the slug from context unless the post is renamed
+	if newPost.Slug == "" {
+		newPost.Slug = post.Slug
 	}
-	if data.Published == nil {
-		newPost.Published = post.Published
+	if newPost.Tags == nil {
+		newPost.Tags = []string{}
 	}
-	if newPost.PublishedAt == "" {
+	if newPost.Published && newPost.PublishedAt == "" && post.Published {
 		newPost.PublishedAt = post.PublishedAt
 	}
+	// The cover images are derived from the uploaded cover, so they go away
+	// with it.
+	if newPost.CoverUrl != nil && post.CoverUrl != nil && *newPost.CoverUrl == *post.CoverUrl {
+		newPost.CoverImages = post.CoverImages
+	}
+
+	authorId := data.Author
+	if authorId == "" {
+		authorId = post.Author.UserId
+	}
+	coAuthorIds := []string{}
+	for _, coAuthorId := range data.Co_Authors {
+		if coAuthorId != authorId {
+			coAuthorIds = append(coAuthorIds, coAuthorId)
+		}
+	}
 
 	// if not the original author or blog's admin, they can't change authors
-	if !auth.IsAdmin(r) && author.UserId != post.Author.UserId && (data.Author != "" || data.Co_Authors != nil) {
-		render.Render(w, r, resp.ErrForbidden(errors.New("you must be the original author in order to change authors")))
+	authorsChanged := authorId != post.Author.UserId || !sameAuthors(coAuthorIds, post.Co_Authors)
+	if authorsChanged && !auth.IsAdmin(r) && author.UserId != post.Author.UserId {
+		middleware.RenderError(w, r, models.Forbidden("you must be the original author in order to change authors"))
 		return
 	}
 
-	if data.Author == "" {
+	if authorId == post.Author.UserId {
 		newPost.Author = post.Author
 	} else {
 		// The original author is making another author the original author.
-		newOriginalAuthor, err := p.authors.Get(data.Author)
+		newOriginalAuthor, err := p.authors.Get(authorId)
 		if err != nil {
-			render.Render(w, r, resp.ErrNotFoundCustom(fmt.Errorf("couldn't find author with user_id of %s", data.Author)))
+			middleware.RenderError(w, r, err)
 			return
 		}
 		newPost.Author = newOriginalAuthor
 	}
 
-	if data.Co_Authors == nil {
-		newPost.Co_Authors = post.Co_Authors
-	} else {
-		authors, missingAuthorId, err := p.AuthorIdsToAuthors(data.Co_Authors)
-		if err != nil {
-			if missingAuthorId != nil {
-				render.Render(w, r, resp.ErrNotFoundCustom(fmt.Errorf("couldn't find author with user_id of %s", *missingAuthorId)))
-				return
-			}
-			render.Render(w, r, resp.ErrInternal(err))
-			panic(err)
-		}
-
-		newPost.Co_Authors = authors
+	authors, err := p.AuthorIdsToAuthors(coAuthorIds)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
 	}
+	newPost.Co_Authors = authors
 
-	err := p.posts.Update(newPost)
+	replacedCover, err := p.posts.Update(post.Slug, newPost, author.UserId, version)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
+	}
+	if replacedCover != nil {
+		if err := imaging.Delete(r.Context(), p.blobs, replacedCover.Key, replacedCover.Derivatives); err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
 	}
 
-	postResp, err := p.NewPostResponse(newPost)
+	postResp, err := p.NewPostResponse(r.Context(), newPost, models.AllPostFields)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
+	middleware.SetVersion(w, newPost.Version)
 	render.Render(w, r, postResp)
 }
 
+// sameAuthors reports whether ids are the user ids of authors, in any order.
+func sameAuthors(ids []string, authors []*models.Author) bool {
+	set := map[string]struct{}{}
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	if len(set) != len(authors) {
+		return false
+	}
+	for _, author := range authors {
+		if _, ok := set[author.UserId]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// PostDelete moves the post to the trash, from which it can be restored until
+// it is purged.
 func (p *Posts) PostDelete(w http.ResponseWriter, r *http.Request) {
 	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
 
-	err := p.posts.Delete(post.Slug)
+	err := p.posts.Trash(post.Slug, author.UserId)
 	if err != nil {
-		render.Render(w, r, resp.ErrInternal(err))
-		panic(err)
+		middleware.RenderError(w, r, err)
+		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
@@ -215,25 +350,81 @@ type PostRequest struct {
 	Co_Authors []string `json:"co_authors"`
 }
 
+// newPostRequest returns the request that would replace the post with
+// itself.
+func newPostRequest(post *models.Post) *PostRequest {
+	coAuthorIds := []string{}
+	for _, coAuthor := range post.Co_Authors {
+		coAuthorIds = append(coAuthorIds, coAuthor.UserId)
+	}
+	postCopy := *post
+	// The slug is left out so that a patch only renames the post if it sets
+	// one.
+	postCopy.Slug = ""
+	return &PostRequest{
+		Post:       &postCopy,
+		Published:  &postCopy.Published,
+		Author:     post.Author.UserId,
+		Co_Authors: coAuthorIds,
+	}
+}
+
 func (pr *PostRequest) Bind(r *http.Request) error {
 	if pr == nil {
 		return errors.New("missing required Post fields")
 	}
+	if pr.Post == nil {
+		pr.Post = &models.Post{}
+	}
 
-	if pr.Post != nil && pr.Post.PublishedAt != "" {
-		_, err := time.Parse(constants.PublishedAtFormat, pr.Post.PublishedAt)
-		if err != nil {
-			return fmt.Errorf("time must be in the format of %s", constants.PublishedAtFormat)
-		}
+	if err := pr.validate(r.Method == http.MethodPost); err != nil {
+		return err
 	}
 
 	if pr.Published != nil {
 		pr.Post.Published = *pr.Published
 	}
 
+	if pr.Post.Tags != nil {
+		pr.Post.Tags = uniqueStrings(pr.Post.Tags)
+	}
+
+	// Cover images are only generated when the cover is uploaded.
+	pr.Post.CoverImages = nil
+
 	return nil
 }
 
+// validate checks the fields of the request. New posts need all of their
+// content, while replacements keep the slug of the post if they have none.
+func (pr *PostRequest) validate(creating bool) error {
+	post := pr.Post
+	var coverUrl string
+	if post.CoverUrl != nil {
+		coverUrl = *post.CoverUrl
+	}
+	return validation.Validate(
+		validation.Field("slug", post.Slug, validation.RequiredIf(creating), validation.MaxLength(maxSlugLength), validation.PostSlug),
+		validation.Field("title", post.Title, validation.Required, validation.MaxLength(maxTitleLength)),
+		validation.Field("excerpt", post.Excerpt, validation.RequiredIf(creating), validation.MaxLength(maxExcerptLength)),
+		validation.Field("content", post.Content, validation.RequiredIf(creating), validation.MaxBytes(maxContentBytes)),
+		validation.Field("published_at", post.PublishedAt, validation.Time(constants.PublishedAtFormat)),
+		validation.Field("cover_url", coverUrl, validation.MaxLength(maxUrlLength), validation.URL("http", "https")),
+		validation.Each("tags", post.Tags, validation.Required, validation.MaxLength(maxSlugLength), validation.Slug),
+		validation.Field("author", pr.Author, validation.MaxLength(maxUserIdLength)),
+		validation.Each("co_authors", pr.Co_Authors, validation.Required, validation.MaxLength(maxUserIdLength)),
+	)
+}
+
+// parsePostFields reads the fields query parameter of the request, falling
+// back to defaultFields if there is none.
+func parsePostFields(r *http.Request, defaultFields models.PostFields) (models.PostFields, error) {
+	if _, ok := r.URL.Query()["fields"]; !ok {
+		return defaultFields, nil
+	}
+	return models.ParsePostFields(r.URL.Query().Get("fields"))
+}
+
 type PostResponse struct {
 	*models.Post
 	Co_Authors []*AuthorResponse `json:"co_authors"`
@@ -241,53 +432,149 @@ type PostResponse struct {
 	// last_post_slug and next_post_slug are nullable, semantically.
 	LastPostSlug *string `json:"last_post_slug"`
 	NextPostSlug *string `json:"next_post_slug"`
+
+	// Computed from the rendered content, when selected
+	ContentHtml string             `json:"content_html"`
+	Toc         []markdown.Heading `json:"toc"`
+	WordCount   int                `json:"word_count"`
+	ReadingTime int                `json:"reading_time"`
+
+	fields models.PostFields
 }
 
 func (resp *PostResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-func (p *Posts) NewPostResponse(post *models.Post) (*PostResponse, error) {
-	resp := &PostResponse{Post: post}
+// MarshalJSON only outputs the selected fields of the post, in the order
+// they have without fields.
+func (resp *PostResponse) MarshalJSON() ([]byte, error) {
+	type postResponse PostResponse
+	if resp.fields == nil {
+		return json.Marshal((*postResponse)(resp))
+	}
+
+	post := resp.Post
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, field := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"slug", post.Slug},
+		{"title", post.Title},
+		{"excerpt", post.Excerpt},
+		{"content", post.Content},
+		{"published", post.Published},
+		{"published_at", post.PublishedAt},
+		{"modified_at", post.ModifiedAt},
+		{"author", post.Author},
+		{"cover_url", post.CoverUrl},
+		{"cover_images", post.CoverImages},
+		{"tags", post.Tags},
+		{"scheduled", post.Scheduled},
+		{"version", post.Version},
+		{"co_authors", resp.Co_Authors},
+		{"last_post_slug", resp.LastPostSlug},
+		{"next_post_slug", resp.NextPostSlug},
+		{"content_html", resp.ContentHtml},
+		{"toc", resp.Toc},
+		{"word_count", resp.WordCount},
+		{"reading_time", resp.ReadingTime},
+	} {
+		if !resp.fields.Has(field.name) {
+			continue
+		}
+		// published_at is omitted when empty, as its JSON tag says.
+		if field.name == "published_at" && post.PublishedAt == "" {
+			continue
+		}
+		b, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + field.name + `":`)
+		buf.Write(b)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// PostCursorPageResponse is a page of posts listed by cursor. next_cursor is
+// null on the last page.
+type PostCursorPageResponse struct {
+	Posts      []render.Renderer `json:"posts"`
+	NextCursor *string           `json:"next_cursor"`
+}
 
-	// Fetch post's co_authors
-	// co_authors, err := p.authors.CoAuthorsOfPost(post.Slug)
-	// if err != nil {
-	// 	return nil, err
-	// }
-	// co_authorsResp := NewAuthorListResponse(co_authors)
-	co_authorsResp := NewAuthorListResponse(post.Co_Authors)
-	resp.Co_Authors = co_authorsResp
+func (resp *PostCursorPageResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
 
-	// Fetch LastPostSlug & NextPostSlug
-	last, next, err := p.posts.GetLastAndNextPostSlug(post.Slug)
+func (p *Posts) NewPostResponse(ctx context.Context, post *models.Post, fields models.PostFields) (*PostResponse, error) {
+	list, err := p.NewPostListResponse(ctx, []*models.Post{post}, fields)
 	if err != nil {
 		return nil, err
 	}
-	if last != "" {
-		resp.LastPostSlug = &last
-	}
-	if next != "" {
-		resp.NextPostSlug = &next
-	}
-
-	return resp, nil
+	return list[0].(*PostResponse), nil
 }
 
-func (p *Posts) NewPostListResponse(posts []*models.Post) ([]render.Renderer, error) {
-	list := []render.Renderer{}
-	for _, post := range posts {
-		postResp, err := p.NewPostResponse(post)
+// NewPostListResponse fetches the neighbours of all posts at once, so the
+// number of queries doesn't grow with the number of posts. Content is rendered
+// when fields need it.
+func (p *Posts) NewPostListResponse(ctx context.Context, posts []*models.Post, fields models.PostFields) ([]render.Renderer, error) {
+	neighbours := map[string]models.PostNeighbours{}
+	if fields.Has("last_post_slug") || fields.Has("next_post_slug") {
+		slugs := make([]string, 0, len(posts))
+		for _, post := range posts {
+			slugs = append(slugs, post.Slug)
+		}
+		var err error
+		neighbours, err = p.posts.GetLastAndNextPostSlugs(slugs)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	list := []render.Renderer{}
+	for _, post := range posts {
+		postResp := newPostResponse(post, neighbours[post.Slug], fields)
+		if fields.IsRendered() {
+			doc := p.renderings.Render(ctx, "posts:"+post.Slug+":"+post.ModifiedAt, post.Content)
+			postResp.ContentHtml = doc.HTML
+			postResp.Toc = doc.TOC
+			postResp.WordCount = doc.WordCount
+			postResp.ReadingTime = doc.ReadingTime()
+		}
 		list = append(list, postResp)
 	}
 	return list, nil
 }
 
+func newPostResponse(post *models.Post, neighbours models.PostNeighbours, fields models.PostFields) *PostResponse {
+	resp := &PostResponse{Post: post, fields: fields}
+
+	resp.Co_Authors = NewAuthorListResponse(post.Co_Authors)
+
+	if neighbours.Last != "" {
+		resp.LastPostSlug = &neighbours.Last
+	}
+	if neighbours.Next != "" {
+		resp.NextPostSlug = &neighbours.Next
+	}
+
+	return resp
+}
+
 // AuthorIdsToAuthors returns a list of Author from authorIds
-func (p *Posts) AuthorIdsToAuthors(authorIds []string) (authors []*models.Author, missingAuthorId *string, err error) {
+func (p *Posts) AuthorIdsToAuthors(authorIds []string) (authors []*models.Author, err error) {
+	return authorIdsToAuthors(p.authors, authorIds)
+}
+
+func authorIdsToAuthors(authorModel *models.AuthorModel, authorIds []string) (authors []*models.Author, err error) {
 	var authorIdsSet map[string]struct{} = make(map[string]struct{})
 	for _, authorId := range authorIds {
 		authorIdsSet[authorId] = struct{}{}
@@ -302,19 +589,136 @@ func (p *Posts) AuthorIdsToAuthors(authorIds []string) (authors []*models.Author
 
 	// Assure that all of the authors in the request are valid
 	for _, authorId := range authorIds {
-		author, err := p.authors.Get(authorId)
+		author, err := authorModel.Get(authorId)
 		if err != nil {
-			return nil, &authorId, err
+			return nil, err
 		}
 		authors = append(authors, author)
 	}
 
-	return authors, nil, nil
+	return authors, nil
+}
+
+// postFilterOf returns the filter of the posts visible to the request. Drafts
+// and scheduled posts are only visible to their authors and admins.
+func postFilterOf(r *http.Request) models.PostFilter {
+	filter := models.PostFilter{ViewerIsAdmin: auth.IsAdmin(r)}
+	if author, ok := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author); ok {
+		filter.ViewerUserId = author.UserId
+	}
+	return filter
+}
+
+const (
+	defaultPreviewExpiration = 7 * 24 * time.Hour
+	maxPreviewExpiration     = 30 * 24 * time.Hour
+)
+
+// PostPreviewPost mints a preview token, which lets anyone holding it read the
+// post before it is live.
+func (p *Posts) PostPreviewPost(w http.ResponseWriter, r *http.Request) {
+	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+
+	data := &PreviewRequest{}
+	if r.ContentLength != 0 {
+		if err := render.Bind(r, data); err != nil {
+			render.Render(w, r, resp.ErrBadRequest(err))
+			return
+		}
+	}
+
+	expiration := defaultPreviewExpiration
+	if data.ExpiresIn != 0 {
+		expiration = time.Duration(data.ExpiresIn) * time.Second
+	}
+	expiresAt := time.Now().Add(expiration)
+	token := p.previewTokens.New(post.Slug, expiresAt)
+
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, &PreviewResponse{
+		Token:     token,
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+		Url:       "/posts/" + url.PathEscape(post.Slug) + "?preview=" + url.QueryEscape(token),
+	})
+}
+
+type PreviewRequest struct {
+	// ExpiresIn is the number of seconds the token is valid for.
+	ExpiresIn int64 `json:"expires_in"`
+}
+
+func (pr *PreviewRequest) Bind(r *http.Request) error {
+	if pr.ExpiresIn < 0 || time.Duration(pr.ExpiresIn)*time.Second > maxPreviewExpiration {
+		return fmt.Errorf("expires_in must be between 1 and %d seconds", int64(maxPreviewExpiration.Seconds()))
+	}
+
+	return nil
+}
+
+type PreviewResponse struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expires_at"`
+	Url       string `json:"url"`
+}
+
+func (resp *PreviewResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// uniqueStrings removes duplicated strings, keeping the first occurrence.
+func uniqueStrings(strs []string) []string {
+	seen := make(map[string]struct{}, len(strs))
+	unique := []string{}
+	for _, s := range strs {
+		if _, ok := seen[s]; ok {
+			continue
+		}
+		seen[s] = struct{}{}
+		unique = append(unique, s)
+	}
+	return unique
+}
+
+// PostsOfTagGet lists the posts tagged with the tag in the context.
+func (p *Posts) PostsOfTagGet(w http.ResponseWriter, r *http.Request) {
+	tag := r.Context().Value(middleware.TagCtxKey{}).(*models.Tag)
+
+	opts, err := parsePageOptions(r, defaultPostSort, models.ParsePostSort)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	fields, err := parsePostFields(r, models.PostSummaryFields)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	filter := postFilterOf(r)
+	filter.Tag = tag.Slug
+	modelPosts, total, err := p.posts.Page(*opts, filter, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	postsResp, err := p.NewPostListResponse(r.Context(), modelPosts, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	setPaginationHeaders(w, r, opts, total)
+	render.RenderList(w, r, postsResp)
 }
 
-func NewPosts(posts *models.PostModel, authors *models.AuthorModel) *Posts {
+func NewPosts(posts *models.PostModel, authors *models.AuthorModel, previewTokens *auth.PreviewTokens, blobs storage.Storage, renderings *markdown.Cache) *Posts {
 	return &Posts{
-		posts:   posts,
-		authors: authors,
+		posts:         posts,
+		authors:       authors,
+		previewTokens: previewTokens,
+		blobs:         blobs,
+		renderings:    renderings,
 	}
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"hxann.com/blog/models"
+)
+
+func TestPostResponseMarshalJSON(t *testing.T) {
+	allFields := models.AllPostFields.With(models.HTMLPostFields...)
+	draft := testPost()
+	draft.Published = false
+	draft.PublishedAt = ""
+
+	for _, post := range []*models.Post{testPost(), draft} {
+		all, err := json.Marshal(newPostResponse(post, models.PostNeighbours{Last: "last"}, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		selected, err := json.Marshal(newPostResponse(post, models.PostNeighbours{Last: "last"}, allFields))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(selected) != string(all) {
+			t.Errorf("got %s with every field selected, want %s", selected, all)
+		}
+	}
+
+	b, err := json.Marshal(newPostResponse(testPost(), models.PostNeighbours{}, models.NewPostFields("version", "title", "next_post_slug")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"slug":"post","title":"Title","version":3,"next_post_slug":null}`; string(b) != want {
+		t.Errorf("got %s, want %s", b, want)
+	}
+}
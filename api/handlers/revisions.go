@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/diff"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+)
+
+type Revisions struct {
+	revisions *models.RevisionModel
+	posts     *Posts
+}
+
+func (rv *Revisions) RevisionsGet(w http.ResponseWriter, r *http.Request) {
+	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+
+	revisions, err := rv.revisions.AllOfPost(post.Slug)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	list := []render.Renderer{}
+	for _, revision := range revisions {
+		list = append(list, NewRevisionResponse(revision))
+	}
+
+	render.RenderList(w, r, list)
+}
+
+func (rv *Revisions) RevisionGet(w http.ResponseWriter, r *http.Request) {
+	revision := r.Context().Value(middleware.RevisionCtxKey{}).(*models.Revision)
+
+	render.Render(w, r, NewRevisionResponse(revision))
+}
+
+// RevisionDiffGet compares the revision in the context with the revision of the
+// from query parameter, or with the revision right before it if there is none.
+// An unknown from revision is not found, while the first revision is compared
+// with an empty one.
+func (rv *Revisions) RevisionDiffGet(w http.ResponseWriter, r *http.Request) {
+	revision := r.Context().Value(middleware.RevisionCtxKey{}).(*models.Revision)
+
+	var from *models.Revision
+	var err error
+	if fromParam := r.URL.Query().Get("from"); fromParam != "" {
+		fromId, parseErr := strconv.ParseInt(fromParam, 10, 64)
+		if parseErr != nil {
+			render.Render(w, r, resp.ErrBadRequest(errors.New("from must be a revision id")))
+			return
+		}
+		from, err = rv.revisions.Get(revision.PostSlug, fromId)
+	} else {
+		from, err = rv.revisions.Previous(revision.PostSlug, revision.Id)
+		if errors.Is(err, models.ErrNotFound) {
+			// Diffing the first revision against nothing shows it as entirely new.
+			from, err = &models.Revision{PostSlug: revision.PostSlug}, nil
+		}
+	}
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, &RevisionDiffResponse{
+		From:    from.Id,
+		To:      revision.Id,
+		Title:   diff.Lines(from.Title, revision.Title),
+		Excerpt: diff.Lines(from.Excerpt, revision.Excerpt),
+		Content: diff.Lines(from.Content, revision.Content),
+	})
+}
+
+// RevisionRestorePost sets the title, excerpt and content of the post back to
+// the revision's. The restore is itself recorded as a new revision.
+func (rv *Revisions) RevisionRestorePost(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+	post := r.Context().Value(middleware.PostCtxKey{}).(*models.Post)
+	revision := r.Context().Value(middleware.RevisionCtxKey{}).(*models.Revision)
+
+	version, ok := ifMatchVersion(r, post.Version)
+	if !ok {
+		middleware.RenderError(w, r, models.ErrStaleVersion)
+		return
+	}
+
+	newPost := *post
+	newPost.Title = revision.Title
+	newPost.Excerpt = revision.Excerpt
+	newPost.Content = revision.Content
+
+	replacedCover, err := rv.posts.posts.Update(post.Slug, &newPost, author.UserId, version)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+	if replacedCover != nil {
+		if err := imaging.Delete(r.Context(), rv.posts.blobs, replacedCover.Key, replacedCover.Derivatives); err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
+	}
+
+	postResp, err := rv.posts.NewPostResponse(r.Context(), &newPost, models.AllPostFields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	middleware.SetVersion(w, newPost.Version)
+	render.Render(w, r, postResp)
+}
+
+type RevisionResponse struct {
+	*models.Revision
+}
+
+func (resp *RevisionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewRevisionResponse(revision *models.Revision) *RevisionResponse {
+	return &RevisionResponse{Revision: revision}
+}
+
+// RevisionDiffResponse is the line-level diff of each field between two
+// revisions. from is 0 when there is no revision to compare with.
+type RevisionDiffResponse struct {
+	From    int64       `json:"from"`
+	To      int64       `json:"to"`
+	Title   []diff.Line `json:"title"`
+	Excerpt []diff.Line `json:"excerpt"`
+	Content []diff.Line `json:"content"`
+}
+
+func (resp *RevisionDiffResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewRevisions(revisions *models.RevisionModel, posts *Posts) *Revisions {
+	return &Revisions{
+		revisions: revisions,
+		posts:     posts,
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/models"
+)
+
+const (
+	maxSearchQueryLength = 200
+	// snippetRadius is how many characters are kept on each side of the first
+	// match of a snippet.
+	snippetRadius = 80
+)
+
+var errSearchSort = errors.New("search results are always sorted by relevance")
+
+// PostsSearchGet searches published posts by their title, excerpt and content.
+func (p *Posts) PostsSearchGet(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		render.Render(w, r, resp.ErrBadRequest(errors.New("q is required")))
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		render.Render(w, r, resp.ErrBadRequest(errors.New("q is too long")))
+		return
+	}
+
+	opts, err := parsePageOptions(r, models.Sort{}, func(string) (models.Sort, error) {
+		return models.Sort{}, errSearchSort
+	})
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	fields, err := parsePostFields(r, models.PostSummaryFields)
+	if err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	results, total, err := p.posts.Search(query, *opts, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	posts := make([]*models.Post, 0, len(results))
+	for _, result := range results {
+		posts = append(posts, result.Post)
+	}
+	postsResp, err := p.NewPostListResponse(r.Context(), posts, fields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	terms := searchTerms(query)
+	list := []render.Renderer{}
+	for i, result := range results {
+		snippet := highlight(result.Post.Content, terms)
+		if snippet == "" {
+			snippet = highlight(result.Post.Excerpt, terms)
+		}
+		list = append(list, &PostSearchResultResponse{
+			Post:    postsResp[i].(*PostResponse),
+			Score:   result.Score,
+			Snippet: snippet,
+		})
+	}
+
+	setPaginationHeaders(w, r, opts, total)
+	render.RenderList(w, r, list)
+}
+
+type PostSearchResultResponse struct {
+	Post  *PostResponse `json:"post"`
+	Score float64       `json:"score"`
+	// Snippet is an HTML-escaped excerpt of where the post matches, with the
+	// matching words wrapped in <mark>.
+	Snippet string `json:"snippet"`
+}
+
+func (resp *PostSearchResultResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// searchTerms splits a search query into lowercase words.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(c rune) bool {
+		return !isWordRune(c)
+	})
+}
+
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsNumber(c)
+}
+
+// highlight returns the part of text around the first occurrence of any of
+// terms, with every occurrence wrapped in <mark>. It returns an empty string if
+// none of the terms occur.
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// Lowercasing changed the length, so indices don't line up.
+		lower = runes
+	}
+
+	type match struct{ start, end int }
+	var matches []match
+	for i := 0; i < len(lower); i++ {
+		// Only match from the start of a word
+		if i > 0 && isWordRune(lower[i-1]) {
+			continue
+		}
+		for _, term := range terms {
+			t := []rune(term)
+			if i+len(t) <= len(lower) && string(lower[i:i+len(t)]) == term {
+				matches = append(matches, match{i, i + len(t)})
+				i += len(t) - 1
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	start := matches[0].start - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := matches[0].end + snippetRadius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, m := range matches {
+		if m.start < start {
+			continue
+		}
+		if m.end > end {
+			break
+		}
+		b.WriteString(html.EscapeString(string(runes[pos:m.start])))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(string(runes[m.start:m.end])))
+		b.WriteString("</mark>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(string(runes[pos:end])))
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/models"
+	"hxann.com/blog/sitemap"
+)
+
+// sitemapPaths are the paths of the pages of the website by kind of sitemap
+// entry.
+var sitemapPaths = map[string]string{
+	models.SitemapPost:   "/posts/",
+	models.SitemapPage:   "/pages/",
+	models.SitemapAuthor: "/authors/",
+}
+
+type Sitemaps struct {
+	sitemap *models.SitemapModel
+	site    Site
+	// robots are the rules of robots.txt, which the location of the sitemap
+	// is appended to.
+	robots string
+}
+
+// SitemapGet serves the sitemap of the website. Once there are more pages
+// than a sitemap can hold, it serves a sitemap index of the sitemaps served
+// by SitemapPartGet instead.
+func (s *Sitemaps) SitemapGet(w http.ResponseWriter, r *http.Request) {
+	urls, err := s.urls()
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	var body bytes.Buffer
+	var modified time.Time
+	if len(urls) <= sitemap.MaxURLs {
+		modified = lastModified(urls)
+		err = sitemap.WriteURLSet(&body, urls)
+	} else {
+		var sitemaps []sitemap.URL
+		for n := 1; (n-1)*sitemap.MaxURLs < len(urls); n++ {
+			part := sitemap.URL{
+				Loc:     s.site.URL + "/sitemap-" + strconv.Itoa(n) + ".xml",
+				LastMod: lastModified(sitemapPart(urls, n)),
+			}
+			if part.LastMod.After(modified) {
+				modified = part.LastMod
+			}
+			sitemaps = append(sitemaps, part)
+		}
+		err = sitemap.WriteIndex(&body, sitemaps)
+	}
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	serveDocument(w, r, sitemap.ContentType, modified, body.Bytes())
+}
+
+// SitemapPartGet serves the nth sitemap of the sitemap index, starting at 1.
+// Sitemaps only exist when the website is too large for a single one.
+func (s *Sitemaps) SitemapPartGet(w http.ResponseWriter, r *http.Request) {
+	urls, err := s.urls()
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	n, err := strconv.Atoi(chi.URLParam(r, "n"))
+	if err != nil || n < 1 || len(urls) <= sitemap.MaxURLs || (n-1)*sitemap.MaxURLs >= len(urls) {
+		render.Render(w, r, resp.ErrNotFound())
+		return
+	}
+	urls = sitemapPart(urls, n)
+
+	var body bytes.Buffer
+	if err := sitemap.WriteURLSet(&body, urls); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	serveDocument(w, r, sitemap.ContentType, lastModified(urls), body.Bytes())
+}
+
+// RobotsGet serves robots.txt, pointing crawlers to the sitemap.
+func (s *Sitemaps) RobotsGet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(strings.TrimRight(s.robots, "\n") + "\n\nSitemap: " + s.site.URL + "/sitemap.xml\n"))
+}
+
+// urls returns the URLs of every public page of the website.
+func (s *Sitemaps) urls() ([]sitemap.URL, error) {
+	entries, err := s.sitemap.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make([]sitemap.URL, len(entries))
+	for i, entry := range entries {
+		urls[i] = sitemap.URL{
+			Loc:     s.site.URL + sitemapPaths[entry.Kind] + url.PathEscape(entry.Key),
+			LastMod: parseLocalTime(entry.ModifiedAt),
+		}
+	}
+	return urls, nil
+}
+
+// sitemapPart returns the URLs of the nth sitemap of an index.
+func sitemapPart(urls []sitemap.URL, n int) []sitemap.URL {
+	end := n * sitemap.MaxURLs
+	if end > len(urls) {
+		end = len(urls)
+	}
+	return urls[(n-1)*sitemap.MaxURLs : end]
+}
+
+// lastModified returns the time the most recently modified of urls was.
+func lastModified(urls []sitemap.URL) time.Time {
+	var modified time.Time
+	for _, u := range urls {
+		if u.LastMod.After(modified) {
+			modified = u.LastMod
+		}
+	}
+	return modified
+}
+
+func NewSitemaps(sitemap *models.SitemapModel, site Site, robots string) *Sitemaps {
+	return &Sitemaps{
+		sitemap: sitemap,
+		site:    site,
+		robots:  robots,
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/models"
+	"hxann.com/blog/validation"
+)
+
+type Tags struct {
+	tags *models.TagModel
+}
+
+func (t *Tags) TagsGet(w http.ResponseWriter, r *http.Request) {
+	modelTags, err := t.tags.All()
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.RenderList(w, r, NewTagListResponse(modelTags))
+}
+
+func (t *Tags) TagsPost(w http.ResponseWriter, r *http.Request) {
+	data := &TagRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	tag := data.Tag
+
+	if err := t.tags.Add(tag); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+	tag.PostCount = 0
+
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewTagResponse(tag))
+}
+
+func (t *Tags) TagGet(w http.ResponseWriter, r *http.Request) {
+	tag := r.Context().Value(middleware.TagCtxKey{}).(*models.Tag)
+
+	render.Render(w, r, NewTagResponse(tag))
+}
+
+func (t *Tags) TagPut(w http.ResponseWriter, r *http.Request) {
+	tag := r.Context().Value(middleware.TagCtxKey{}).(*models.Tag)
+
+	data := &TagRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, resp.ErrBadRequest(err))
+		return
+	}
+
+	newTag := data.Tag
+	if newTag == nil {
+		newTag = &models.Tag{}
+	}
+	// Provides the slug from context
+	newTag.Slug = tag.Slug
+	newTag.PostCount = tag.PostCount
+	// Fill in missing fields
+	if newTag.Name == "" {
+		newTag.Name = tag.Name
+	}
+
+	if err := t.tags.Update(newTag); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, NewTagResponse(newTag))
+}
+
+func (t *Tags) TagDelete(w http.ResponseWriter, r *http.Request) {
+	tag := r.Context().Value(middleware.TagCtxKey{}).(*models.Tag)
+
+	if err := t.tags.Delete(tag.Slug); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type TagRequest struct {
+	*models.Tag
+}
+
+func (tr *TagRequest) Bind(r *http.Request) error {
+	if tr == nil {
+		return errors.New("missing required Tag fields")
+	}
+	if tr.Tag == nil {
+		tr.Tag = &models.Tag{}
+	}
+
+	// Replacements keep the slug, and the name if they have none.
+	creating := r.Method == http.MethodPost
+	var checks []validation.Check
+	if creating {
+		checks = append(checks, validation.Field("slug", tr.Slug, validation.Required, validation.MaxLength(maxSlugLength), validation.Slug))
+	}
+	checks = append(checks, validation.Field("name", tr.Name, validation.RequiredIf(creating), validation.MaxLength(maxTagNameLength)))
+	return validation.Validate(checks...)
+}
+
+type TagResponse struct {
+	*models.Tag
+}
+
+func (resp *TagResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewTagResponse(tag *models.Tag) *TagResponse {
+	return &TagResponse{Tag: tag}
+}
+
+func NewTagListResponse(tags []*models.Tag) []render.Renderer {
+	list := []render.Renderer{}
+	for _, tag := range tags {
+		list = append(list, NewTagResponse(tag))
+	}
+	return list
+}
+
+func NewTags(tags *models.TagModel) *Tags {
+	return &Tags{
+		tags: tags,
+	}
+}
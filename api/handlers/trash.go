@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/auth"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+)
+
+// trashedPostFields are the fields of trashed posts. They have no neighbours,
+// as they are not part of the published posts.
+var trashedPostFields = models.PostSummaryFields.Without("last_post_slug", "next_post_slug")
+
+// TrashGet lists the trashed posts of the requesting author, or every trashed
+// post for admins.
+func (p *Posts) TrashGet(w http.ResponseWriter, r *http.Request) {
+	author := r.Context().Value(middleware.RequestAuthorCtxKey{}).(*models.Author)
+
+	trashed, err := p.posts.Trashed(author.UserId, auth.IsAdmin(r))
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	list := []render.Renderer{}
+	for _, trashedPost := range trashed {
+		list = append(list, NewTrashedPostResponse(trashedPost))
+	}
+
+	render.RenderList(w, r, list)
+}
+
+func (p *Posts) TrashedPostGet(w http.ResponseWriter, r *http.Request) {
+	trashedPost := r.Context().Value(middleware.TrashedPostCtxKey{}).(*models.TrashedPost)
+
+	render.Render(w, r, NewTrashedPostResponse(trashedPost))
+}
+
+// TrashedPostRestorePost takes the post out of the trash.
+func (p *Posts) TrashedPostRestorePost(w http.ResponseWriter, r *http.Request) {
+	trashedPost := r.Context().Value(middleware.TrashedPostCtxKey{}).(*models.TrashedPost)
+
+	if err := p.posts.Restore(trashedPost.Post.Slug); err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	post, err := p.posts.Get(trashedPost.Post.Slug)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	postResp, err := p.NewPostResponse(r.Context(), post, models.AllPostFields)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	render.Render(w, r, postResp)
+}
+
+// TrashedPostDelete permanently deletes the post without waiting for it to be
+// purged, along with its uploaded cover.
+func (p *Posts) TrashedPostDelete(w http.ResponseWriter, r *http.Request) {
+	trashedPost := r.Context().Value(middleware.TrashedPostCtxKey{}).(*models.TrashedPost)
+
+	cover, err := p.posts.DeleteTrashed(trashedPost.Post.Slug)
+	if err != nil {
+		middleware.RenderError(w, r, err)
+		return
+	}
+
+	if cover != nil {
+		if err := imaging.Delete(r.Context(), p.blobs, cover.Key, cover.Derivatives); err != nil {
+			middleware.RenderError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type TrashedPostResponse struct {
+	Post      *PostResponse `json:"post"`
+	TrashedAt string        `json:"trashed_at"`
+	TrashedBy string        `json:"trashed_by"`
+}
+
+func (resp *TrashedPostResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+func NewTrashedPostResponse(trashedPost *models.TrashedPost) *TrashedPostResponse {
+	return &TrashedPostResponse{
+		Post:      newPostResponse(trashedPost.Post, models.PostNeighbours{}, trashedPostFields),
+		TrashedAt: trashedPost.TrashedAt,
+		TrashedBy: trashedPost.TrashedBy,
+	}
+}
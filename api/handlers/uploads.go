@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+	"hxann.com/blog/storage"
+)
+
+// readFormFile returns the content and the file name of the given field of the
+// multipart request, or the error response to send. The content is read up to
+// maxSize bytes.
+func readFormFile(r *http.Request, field string, maxSize int) ([]byte, string, render.Renderer) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", resp.ErrBadRequest(errors.New("request must be multipart/form-data"))
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", resp.ErrBadRequest(fmt.Errorf("missing %s form field", field))
+		}
+		if err != nil {
+			return nil, "", resp.ErrBadRequest(err)
+		}
+		if part.FormName() != field {
+			continue
+		}
+
+		content, err := io.ReadAll(io.LimitReader(part, int64(maxSize)+1))
+		if err != nil {
+			return nil, "", resp.ErrBadRequest(err)
+		}
+		if len(content) > maxSize {
+			return nil, "", resp.ErrTooLarge(fmt.Errorf("%s must be at most %d MB", field, maxSize>>20))
+		}
+		if len(content) == 0 {
+			return nil, "", resp.ErrBadRequest(fmt.Errorf("%s is empty", field))
+		}
+		return content, part.FileName(), nil
+	}
+}
+
+// deriveImage generates the derivatives of the image stored under key. Images
+// that can't be decoded, such as WebP ones, have no derivatives.
+func deriveImage(ctx context.Context, blobs storage.Storage, key string, content []byte) (*models.ImageDerivatives, error) {
+	derivatives, err := imaging.Generate(ctx, blobs, key, content)
+	if errors.Is(err, imaging.ErrUnsupportedImage) {
+		return nil, nil
+	}
+	return derivatives, err
+}
+
+// deleteUpload deletes the image stored under key and its derivatives after the
+// request failed to use them. The request's error is the one reported, so a
+// failure to delete is only logged.
+func deleteUpload(r *http.Request, blobs storage.Storage, key string, derivatives *models.ImageDerivatives) {
+	// The request's context may be canceled, which is why it failed.
+	if err := imaging.Delete(context.Background(), blobs, key, derivatives); err != nil {
+		middleware.LogError(r, "failed to delete upload", err)
+	}
+}
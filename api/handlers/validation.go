@@ -0,0 +1,15 @@
+package handlers
+
+// Lengths of columns in schema.sql, which longer values would overflow.
+const (
+	maxSlugLength     = 255
+	maxTagNameLength  = 255
+	maxTitleLength    = 1000
+	maxExcerptLength  = 1000
+	maxContentBytes   = 65535
+	maxUrlLength      = 1000
+	maxUserIdLength   = 500
+	maxFullNameLength = 500
+	maxEmailLength    = 320
+	maxBioLength      = 1000
+)
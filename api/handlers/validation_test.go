@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/render"
+	"hxann.com/blog/validation"
+)
+
+// invalidFields binds body as the body of a request with method, and returns
+// the fields reported as invalid.
+func invalidFields(t *testing.T, method string, body string, v render.Binder) []string {
+	t.Helper()
+	r := httptest.NewRequest(method, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	err := render.Bind(r, v)
+	if err == nil {
+		return nil
+	}
+	var errs validation.Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want validation errors", err)
+	}
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field+":"+err.Code)
+	}
+	return fields
+}
+
+func TestPageRequestBind(t *testing.T) {
+	long := strings.Repeat("a", maxTitleLength+1)
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		fields []string
+	}{
+		{"valid", http.MethodPost, `{"slug":"about","title":"About","excerpt":"E","content":"C"}`, nil},
+		{"empty", http.MethodPost, `{}`, []string{"slug:required", "title:required", "excerpt:required", "content:required"}},
+		// Only posts are routed next to reserved slugs.
+		{"reserved post slug", http.MethodPost, `{"slug":"search","title":"T","excerpt":"E","content":"C"}`, nil},
+		{"invalid slug", http.MethodPost, `{"slug":"About Us","title":"T","excerpt":"E","content":"C"}`, []string{"slug:invalid"}},
+		{"too long", http.MethodPost, `{"slug":"about","title":"` + long + `","excerpt":"` + long + `","content":"C"}`, []string{"title:too_long", "excerpt:too_long"}},
+		{"invalid published_at", http.MethodPost, `{"slug":"about","title":"T","excerpt":"E","content":"C","published_at":"yesterday"}`, []string{"published_at:invalid"}},
+		{"empty co-author", http.MethodPost, `{"slug":"about","title":"T","excerpt":"E","content":"C","co_authors":[""]}`, []string{"co_authors[0]:required"}},
+		// Replacements keep the fields they leave out, and the slug.
+		{"partial replacement", http.MethodPut, `{"title":"New title"}`, nil},
+		{"replacement with a slug", http.MethodPut, `{"slug":"Ignored Slug"}`, nil},
+		{"replacement too long", http.MethodPut, `{"title":"` + long + `"}`, []string{"title:too_long"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := invalidFields(t, test.method, test.body, &PageRequest{})
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("got invalid fields %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestPostRequestBind(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		fields []string
+	}{
+		{"valid", http.MethodPost, `{"slug":"a-post","title":"T","excerpt":"E","content":"C","tags":["go","trash"]}`, nil},
+		{"reserved slug", http.MethodPost, `{"slug":"search","title":"T","excerpt":"E","content":"C"}`, []string{"slug:invalid"}},
+		// Tags are created with their name as their slug.
+		{"invalid tag", http.MethodPost, `{"slug":"a-post","title":"T","excerpt":"E","content":"C","tags":["go","Go Lang"]}`, []string{"tags[1]:invalid"}},
+		{"invalid tag in a replacement", http.MethodPut, `{"title":"T","tags":["a/b"]}`, []string{"tags[0]:invalid"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := invalidFields(t, test.method, test.body, &PostRequest{})
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("got invalid fields %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestTagRequestBind(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		fields []string
+	}{
+		{"valid", http.MethodPost, `{"slug":"go","name":"Go"}`, nil},
+		{"empty", http.MethodPost, `{}`, []string{"slug:required", "name:required"}},
+		{"reserved post slug", http.MethodPost, `{"slug":"trash","name":"Trash"}`, nil},
+		{"invalid slug", http.MethodPost, `{"slug":"Go Lang","name":"Go"}`, []string{"slug:invalid"}},
+		{"too long", http.MethodPost, `{"slug":"` + strings.Repeat("a", maxSlugLength+1) + `","name":"` + strings.Repeat("a", maxTagNameLength+1) + `"}`, []string{"slug:too_long", "name:too_long"}},
+		{"rename", http.MethodPut, `{"name":"Golang"}`, nil},
+		{"replacement without a name", http.MethodPut, `{}`, nil},
+		{"rename too long", http.MethodPut, `{"name":"` + strings.Repeat("a", maxTagNameLength+1) + `"}`, []string{"name:too_long"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fields := invalidFields(t, test.method, test.body, &TagRequest{})
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Errorf("got invalid fields %v, want %v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ifMatchVersion returns the version that the If-Match header of the request
+// requires the resource to still be at, or 0 when it may be overwritten
+// regardless. The entity tag of a version is the version in quotes, such as
+// "3", or followed by the hash of the body of a GET response, as in "3-<hash>".
+// Among several, the one of the current version is returned. ok is false when
+// no entity tag is a version, as none can match.
+func ifMatchVersion(r *http.Request, current int) (version int, ok bool) {
+	header := strings.Join(r.Header.Values("If-Match"), ",")
+	if header == "" {
+		return 0, true
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return 0, true
+		}
+		// Weak entity tags never match If-Match.
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		tag, _, _ = strings.Cut(tag[1:len(tag)-1], "-")
+		n, err := strconv.Atoi(tag)
+		if err != nil || n < 1 {
+			continue
+		}
+		if version == 0 || n == current {
+			version = n
+		}
+	}
+
+	return version, version != 0
+}
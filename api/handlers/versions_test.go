@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch []string
+		version int
+		ok      bool
+	}{
+		{"none", nil, 0, true},
+		{"any", []string{"*"}, 0, true},
+		{"current", []string{`"3"`}, 3, true},
+		{"stale", []string{`"2"`}, 2, true},
+		{"GET ETag", []string{`"3-0123456789abcdef0123456789abcdef"`}, 3, true},
+		{"stale GET ETag", []string{`"2-0123456789abcdef0123456789abcdef"`}, 2, true},
+		{"current among several", []string{`"1", "3-abc"`, `"2"`}, 3, true},
+		{"weak", []string{`W/"3"`}, 0, false},
+		{"not a version", []string{`"0123456789abcdef0123456789abcdef"`}, 0, false},
+		{"zero", []string{`"0"`}, 0, false},
+		{"unquoted", []string{"3"}, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/posts/x", nil)
+			for _, value := range test.ifMatch {
+				r.Header.Add("If-Match", value)
+			}
+			version, ok := ifMatchVersion(r, 3)
+			if version != test.version || ok != test.ok {
+				t.Errorf("got %d, %t, want %d, %t", version, ok, test.version, test.ok)
+			}
+		})
+	}
+}
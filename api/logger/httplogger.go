@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/felixge/httpsnoop"
+	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 )
 
@@ -23,6 +24,8 @@ type HTTPReqInfo struct {
 	Size      int64
 	Duration  time.Duration
 	UserAgent string
+	// RequestId is the id of the request sent in error responses.
+	RequestId string
 }
 
 // ipAddrFromRemoteAddr removes the port from the address.
@@ -63,6 +66,7 @@ func (logger *HTTPLogger) LogRequestHandler(h http.Handler) http.Handler {
 			Uri:       r.URL.String(),
 			Referer:   r.Header.Get("Referer"),
 			UserAgent: r.Header.Get("User-Agent"),
+			RequestId: middleware.GetReqID(r.Context()),
 		}
 
 		ri.Ip = requestGetRemoteAddress(r)
@@ -95,5 +99,6 @@ func (logger *HTTPLogger) LogHTTPReq(ri *HTTPReqInfo) {
 		"size", ri.Size,
 		"duration", ri.Duration.Milliseconds(),
 		"ua", ri.UserAgent,
+		"request_id", ri.RequestId,
 	)
 }
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// privateCacheControl is the Cache-Control of responses that depend on who
+// requests them, such as drafts shown to their authors.
+const privateCacheControl = "private, no-cache"
+
+// bufferedResponseWriter holds the response back until it is complete, so
+// that its ETag can be computed from its body.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// flush sends the response as the handler wrote it.
+func (w *bufferedResponseWriter) flush() {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	w.ResponseWriter.Write(w.body.Bytes())
+}
+
+// ConditionalGet lets clients and caches revalidate successful responses. It
+// sets an ETag computed from the body and Cache-Control, and answers
+// If-None-Match with 304 Not Modified when the response didn't change. The
+// version set by the handler with SetVersion starts the ETag, as in "3-<hash>",
+// so that it can be sent back as If-Match.
+func (m *Middleware) ConditionalGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bw := &bufferedResponseWriter{ResponseWriter: w}
+		done := false
+		// Sends errors rendered before a panic
+		defer func() {
+			if !done {
+				bw.flush()
+			}
+		}()
+		next.ServeHTTP(bw, r)
+		done = true
+
+		if bw.status != http.StatusOK {
+			bw.flush()
+			return
+		}
+
+		sum := sha256.Sum256(bw.body.Bytes())
+		etag := hex.EncodeToString(sum[:16])
+		if version := strings.Trim(w.Header().Get("ETag"), `"`); version != "" {
+			etag = version + "-" + etag
+		}
+		w.Header().Set("ETag", `"`+etag+`"`)
+		w.Header().Add("Vary", "Authorization")
+		if r.Header.Get("Authorization") != "" || r.URL.Query().Get("preview") != "" {
+			w.Header().Set("Cache-Control", privateCacheControl)
+		} else if m.CacheControl != "" {
+			w.Header().Set("Cache-Control", m.CacheControl)
+		}
+
+		WriteConditional(w, r, bw.body.Bytes())
+	})
+}
+
+// SetVersion sets the ETag of the response to the version of the resource, in
+// quotes, to send as If-Match to update it.
+func SetVersion(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// WriteConditional writes body, or 304 Not Modified if the ETag or
+// Last-Modified already set on w match the request's If-None-Match or, without
+// one, If-Modified-Since. Unlike http.ServeContent, it ignores Range: the
+// responses are generated, so parts of them can't be fetched reliably.
+func WriteConditional(w http.ResponseWriter, r *http.Request, body []byte) {
+	if notModified(r, w.Header()) {
+		h := w.Header()
+		delete(h, "Content-Type")
+		delete(h, "Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// notModified reports whether the representation described by header is the
+// one the client has, as in RFC 9110 section 13.2.2.
+func notModified(r *http.Request, header http.Header) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		etag := header.Get("ETag")
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// Weak comparison, so that ETags weakened by proxies still
+			// match.
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !modified.After(since)
+}
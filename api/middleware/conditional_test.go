@@ -0,0 +1,177 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func conditionalHandler(status int, body string) http.Handler {
+	return (&Middleware{CacheControl: "public, max-age=60"}).ConditionalGet(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+}
+
+func serveConditional(h http.Handler, method string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/posts/x", nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestConditionalGet(t *testing.T) {
+	h := conditionalHandler(http.StatusOK, `{"slug":"x"}`)
+
+	w := serveConditional(h, http.MethodGet, nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != `{"slug":"x"}` {
+		t.Fatalf("got %d %q, want 200 with the body", w.Code, w.Body.String())
+	}
+	if etag == "" {
+		t.Fatal("got no ETag")
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Errorf("got Cache-Control %q, want the configured one", got)
+	}
+	if got := w.Header().Get("Last-Modified"); got != "" {
+		t.Errorf("got Last-Modified %q, want none", got)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		header http.Header
+		status int
+		body   string
+	}{
+		{"matching ETag", http.MethodGet, http.Header{"If-None-Match": {etag}}, http.StatusNotModified, ""},
+		{"weak ETag", http.MethodGet, http.Header{"If-None-Match": {"W/" + etag}}, http.StatusNotModified, ""},
+		{"ETag in a list", http.MethodGet, http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified, ""},
+		{"any ETag", http.MethodGet, http.Header{"If-None-Match": {"*"}}, http.StatusNotModified, ""},
+		{"HEAD", http.MethodHead, http.Header{"If-None-Match": {etag}}, http.StatusNotModified, ""},
+		{"other ETag", http.MethodGet, http.Header{"If-None-Match": {`"other"`}}, http.StatusOK, `{"slug":"x"}`},
+		{"HEAD without a match", http.MethodHead, nil, http.StatusOK, ""},
+		// Without Last-Modified, the body can't be known to be older.
+		{"If-Modified-Since", http.MethodGet, http.Header{"If-Modified-Since": {time.Now().UTC().Format(http.TimeFormat)}}, http.StatusOK, `{"slug":"x"}`},
+		{"Range", http.MethodGet, http.Header{"Range": {"bytes=0-3"}}, http.StatusOK, `{"slug":"x"}`},
+		{"Range with If-Range", http.MethodGet, http.Header{"Range": {"bytes=0-3"}, "If-Range": {etag}}, http.StatusOK, `{"slug":"x"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serveConditional(h, test.method, test.header)
+			if w.Code != test.status || w.Body.String() != test.body {
+				t.Errorf("got %d %q, want %d %q", w.Code, w.Body.String(), test.status, test.body)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("got ETag %q, want %q", got, etag)
+			}
+			if w.Code == http.StatusNotModified && w.Header().Get("Content-Type") != "" {
+				t.Errorf("got Content-Type %q on 304", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestConditionalGetChangedBody(t *testing.T) {
+	etag := serveConditional(conditionalHandler(http.StatusOK, `{"slug":"x"}`), http.MethodGet, nil).Header().Get("ETag")
+
+	w := serveConditional(conditionalHandler(http.StatusOK, `{"slug":"y"}`), http.MethodGet, http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusOK || w.Body.String() != `{"slug":"y"}` {
+		t.Errorf("got %d %q, want 200 with the new body", w.Code, w.Body.String())
+	}
+}
+
+func TestConditionalGetPrivate(t *testing.T) {
+	h := conditionalHandler(http.StatusOK, "{}")
+
+	w := serveConditional(h, http.MethodGet, http.Header{"Authorization": {"Bearer token"}})
+	if got := w.Header().Get("Cache-Control"); got != privateCacheControl {
+		t.Errorf("got Cache-Control %q, want %q", got, privateCacheControl)
+	}
+	if got := w.Header().Get("Vary"); got != "Authorization" {
+		t.Errorf("got Vary %q, want Authorization", got)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/posts/x?preview=token", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if got := w.Header().Get("Cache-Control"); got != privateCacheControl {
+		t.Errorf("got Cache-Control %q for a preview, want %q", got, privateCacheControl)
+	}
+}
+
+func TestConditionalGetErrors(t *testing.T) {
+	h := conditionalHandler(http.StatusNotFound, `{"code":"not_found"}`)
+
+	w := serveConditional(h, http.MethodGet, http.Header{"If-None-Match": {"*"}})
+	if w.Code != http.StatusNotFound || w.Body.String() != `{"code":"not_found"}` {
+		t.Errorf("got %d %q, want the 404 as is", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("ETag"); got != "" {
+		t.Errorf("got ETag %q on an error", got)
+	}
+}
+
+func TestWriteConditionalLastModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"unconditional", nil, http.StatusOK},
+		{"same time", http.Header{"If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"later", http.Header{"If-Modified-Since": {modified.Add(time.Hour).Format(http.TimeFormat)}}, http.StatusNotModified},
+		{"earlier", http.Header{"If-Modified-Since": {modified.Add(-time.Second).Format(http.TimeFormat)}}, http.StatusOK},
+		{"invalid", http.Header{"If-Modified-Since": {"yesterday"}}, http.StatusOK},
+		// If-None-Match takes precedence.
+		{"other ETag", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {modified.Format(http.TimeFormat)}}, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"feed"`)
+				w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+				WriteConditional(w, r, []byte("<rss/>"))
+			})
+			w := serveConditional(h, http.MethodGet, test.header)
+			if w.Code != test.status {
+				t.Errorf("got %d, want %d", w.Code, test.status)
+			}
+			if test.status == http.StatusOK && w.Body.String() != "<rss/>" {
+				t.Errorf("got body %q, want the document", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestConditionalGetVersion(t *testing.T) {
+	h := (&Middleware{}).ConditionalGet(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetVersion(w, 3)
+		w.Write([]byte("{}"))
+	}))
+
+	w := serveConditional(h, http.MethodGet, nil)
+	etag := w.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `"3-`) || !strings.HasSuffix(etag, `"`) || len(etag) != len(`"3-`)+32+1 {
+		t.Fatalf("got ETag %s, want the version and the hash of the body", etag)
+	}
+
+	w = serveConditional(h, http.MethodGet, http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("got %d, want 304", w.Code)
+	}
+	// The version alone doesn't tell whether the rest of the body changed.
+	w = serveConditional(h, http.MethodGet, http.Header{"If-None-Match": {`"3"`}})
+	if w.Code != http.StatusOK {
+		t.Errorf("got %d for the version alone, want 200", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+	"hxann.com/blog/api/resp"
+)
+
+type errorLoggerCtxKey struct{}
+
+// Errors sets up RenderError for the request, with a logger that tells which
+// request an internal error failed. It must come after chi's RequestID.
+func (m *Middleware) Errors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := m.Sugar.With(
+			"request_id", chiMiddleware.GetReqID(r.Context()),
+			"method", r.Method,
+			"uri", r.URL.RequestURI(),
+		)
+		ctx := context.WithValue(r.Context(), errorLoggerCtxKey{}, logger)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RenderError renders err as the problem resp.Problem maps it to, and logs it
+// if it is internal. Handlers return right after, without writing anything
+// else.
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	problem := resp.Problem(err)
+	if problem.Status >= http.StatusInternalServerError {
+		LogError(r, "request failed", err)
+	}
+	render.Render(w, r, problem)
+}
+
+// LogError logs err as an internal error of the request that doesn't change its
+// response, like a failure to clean up after another error.
+func LogError(r *http.Request, msg string, err error) {
+	if logger, ok := r.Context().Value(errorLoggerCtxKey{}).(*zap.SugaredLogger); ok {
+		logger.Errorw(msg, "error", err)
+	}
+}
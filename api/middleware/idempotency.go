@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+	"github.com/go-redis/redis/v8"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/models"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// maxIdempotencyKeyLength bounds the keys clients choose, which are
+	// usually UUIDs.
+	maxIdempotencyKeyLength = 255
+	// idempotencyPendingTTL bounds how long a key is held while its request is
+	// processed. Requests take seconds, so a key still pending after that was
+	// held by a server that died, and is released for retries.
+	idempotencyPendingTTL = time.Minute
+)
+
+var (
+	errIdempotencyKeyTooLong = errors.New("Idempotency-Key must be at most 255 characters")
+	errIdempotencyKeyReused  = errors.New("Idempotency-Key was already used for a different request")
+	errIdempotencyInProgress = errors.New("a request with this Idempotency-Key is still being processed")
+)
+
+// idempotentResponse is what is stored under an idempotency key. Until the
+// first request completes, only its fingerprint is stored.
+type idempotentResponse struct {
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotent makes requests with an Idempotency-Key header safe to retry. The
+// response to the first request with a key is stored for IdempotencyTTL and
+// replayed to retries, with an Idempotent-Replayed header. Reusing a key for a
+// different request is rejected with a 422, and retrying while the first
+// request is still being processed with a 409. Server errors aren't stored, so
+// they can be retried. It must come after RequiresAuthor, as keys are scoped
+// to authors.
+func (m *Middleware) Idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			render.Render(w, r, resp.ErrBadRequest(errIdempotencyKeyTooLong))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			render.Render(w, r, resp.ErrBadRequest(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		author := r.Context().Value(RequestAuthorCtxKey{}).(*models.Author)
+		redisKey := "idempotency:" + author.UserId + ":" + r.Method + ":" + r.URL.Path + ":" + key
+		fingerprint := requestFingerprint(r, body)
+
+		stored, err := m.claimKey(r, redisKey, fingerprint)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+
+		switch {
+		case stored == nil:
+			// The first request with this key, processed below
+		case stored.Fingerprint != fingerprint:
+			render.Render(w, r, resp.ErrUnprocessableEntity(errIdempotencyKeyReused))
+			return
+		case !stored.Done:
+			render.Render(w, r, resp.ErrConflict(errIdempotencyInProgress))
+			return
+		default:
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		// Headers set by earlier middlewares, such as CORS, depend on the
+		// request, so only the ones set by the handler are stored.
+		header := w.Header().Clone()
+		bw := &bufferedResponseWriter{ResponseWriter: w}
+		// Stores the response even if the handler panics after it was
+		// written, as what it did is done.
+		defer func() {
+			m.storeResponse(redisKey, fingerprint, handlerHeader(header, bw.Header()), bw)
+			bw.flush()
+		}()
+		next.ServeHTTP(bw, r)
+	})
+}
+
+// claimKey stores the fingerprint of the request under key for
+// idempotencyPendingTTL, and returns nil, unless a request already did. Then,
+// what it stored is returned.
+func (m *Middleware) claimKey(r *http.Request, key string, fingerprint string) (*idempotentResponse, error) {
+	pending, err := json.Marshal(&idempotentResponse{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		first, err := m.RedisClient.SetNX(r.Context(), key, pending, idempotencyPendingTTL).Result()
+		if err != nil || first {
+			return nil, err
+		}
+		stored, err := m.storedResponse(r, key)
+		if err != nil || stored != nil {
+			return stored, err
+		}
+		// Expired in the meantime, so the request is processed as if for
+		// the first time.
+	}
+}
+
+// storedResponse returns what is stored under key, or nil if nothing is.
+func (m *Middleware) storedResponse(r *http.Request, key string) (*idempotentResponse, error) {
+	b, err := m.RedisClient.Get(r.Context(), key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stored idempotentResponse
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// storeResponse stores the response under key, or releases the key if the
+// request failed on the server, so that it can be retried. Clients that time
+// out cancel the request context, so it isn't used, lest their retries find
+// nothing.
+func (m *Middleware) storeResponse(key string, fingerprint string, header http.Header, bw *bufferedResponseWriter) {
+	ctx := context.Background()
+	if bw.status == 0 || bw.status >= http.StatusInternalServerError {
+		if err := m.RedisClient.Del(ctx, key).Err(); err != nil {
+			m.Sugar.Errorf("failed to release idempotency key %s: %v", key, err)
+		}
+		return
+	}
+
+	b, err := json.Marshal(&idempotentResponse{
+		Fingerprint: fingerprint,
+		Done:        true,
+		Status:      bw.status,
+		Header:      header,
+		Body:        bw.body.Bytes(),
+	})
+	if err == nil {
+		err = m.RedisClient.Set(ctx, key, b, m.IdempotencyTTL).Err()
+	}
+	if err != nil {
+		m.Sugar.Errorf("failed to store idempotent response of %s: %v", key, err)
+	}
+}
+
+// handlerHeader returns the headers of header that aren't in before, or with
+// other values.
+func handlerHeader(before, header http.Header) http.Header {
+	set := http.Header{}
+	for name, values := range header {
+		if !equalValues(before[name], values) {
+			set[name] = values
+		}
+	}
+	return set
+}
+
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// requestFingerprint identifies the request by its target and body, which
+// retries must repeat.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
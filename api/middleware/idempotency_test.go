@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"hxann.com/blog/models"
+	"hxann.com/blog/redistest"
+)
+
+// idempotentHandler serves requests with Idempotent, counting those that reach
+// the handler. The handler responds with status, echoing the request body.
+func idempotentHandler(t *testing.T, status int) (*redistest.Server, http.Handler, *int) {
+	server, client := redistest.NewClient(t)
+	m := &Middleware{Sugar: zap.NewNop().Sugar(), RedisClient: client, IdempotencyTTL: time.Minute}
+
+	calls := 0
+	h := m.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/posts/x")
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	// Stands for the CORS middleware, which sets headers for each request.
+	cors := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", r.Header.Get("Origin"))
+		h.ServeHTTP(w, r)
+	})
+	return server, cors, &calls
+}
+
+func serveIdempotent(h http.Handler, key, origin, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/posts/", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotencyKeyHeader, key)
+	}
+	r.Header.Set("Origin", origin)
+	r = r.WithContext(context.WithValue(r.Context(), RequestAuthorCtxKey{}, &models.Author{UserId: "auth0|1"}))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	_, h, calls := idempotentHandler(t, http.StatusCreated)
+
+	w := serveIdempotent(h, "key", "https://a.example", `{"slug":"x"}`)
+	if w.Code != http.StatusCreated || w.Body.String() != `{"slug":"x"}` {
+		t.Fatalf("got %d %q, want 201 with the body", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("the first response is replayed")
+	}
+
+	w = serveIdempotent(h, "key", "https://b.example", `{"slug":"x"}`)
+	if *calls != 1 {
+		t.Errorf("the handler ran %d times, want once", *calls)
+	}
+	if w.Code != http.StatusCreated || w.Body.String() != `{"slug":"x"}` {
+		t.Errorf("got %d %q, want the first response", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Idempotent-Replayed"); got != "true" {
+		t.Errorf("got Idempotent-Replayed %q, want true", got)
+	}
+	if got := w.Header().Get("Location"); got != "/posts/x" {
+		t.Errorf("got Location %q, want the one of the first response", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://b.example" {
+		t.Errorf("got Access-Control-Allow-Origin %q, want the one of the retry", got)
+	}
+}
+
+func TestIdempotentWithoutKey(t *testing.T) {
+	_, h, calls := idempotentHandler(t, http.StatusCreated)
+
+	for i := 0; i < 2; i++ {
+		serveIdempotent(h, "", "", "{}")
+	}
+	if *calls != 2 {
+		t.Errorf("the handler ran %d times, want twice", *calls)
+	}
+}
+
+func TestIdempotentKeyReused(t *testing.T) {
+	_, h, calls := idempotentHandler(t, http.StatusCreated)
+
+	serveIdempotent(h, "key", "", `{"slug":"x"}`)
+	w := serveIdempotent(h, "key", "", `{"slug":"y"}`)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("got %d, want 422", w.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("the handler ran %d times, want once", *calls)
+	}
+
+	// Keys are scoped to the request's path.
+	r := httptest.NewRequest(http.MethodPost, "/pages/", strings.NewReader(`{"slug":"y"}`))
+	r.Header.Set(idempotencyKeyHeader, "key")
+	r = r.WithContext(context.WithValue(r.Context(), RequestAuthorCtxKey{}, &models.Author{UserId: "auth0|1"}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Errorf("got %d for another path, want 201", w.Code)
+	}
+}
+
+func TestIdempotentInProgress(t *testing.T) {
+	server, h, calls := idempotentHandler(t, http.StatusCreated)
+
+	r := httptest.NewRequest(http.MethodPost, "/posts/", strings.NewReader("{}"))
+	pending, _ := json.Marshal(&idempotentResponse{Fingerprint: requestFingerprint(r, []byte("{}"))})
+	redisKey := "idempotency:auth0|1:POST:/posts/:key"
+	server.Set(redisKey, string(pending), time.Minute)
+
+	w := serveIdempotent(h, "key", "", "{}")
+	if w.Code != http.StatusConflict {
+		t.Errorf("got %d, want 409", w.Code)
+	}
+	if *calls != 0 {
+		t.Errorf("the handler ran %d times, want never", *calls)
+	}
+
+	// Once the key expires, the request is processed.
+	server.Expire(redisKey)
+	w = serveIdempotent(h, "key", "", "{}")
+	if w.Code != http.StatusCreated || *calls != 1 {
+		t.Errorf("got %d after %d calls, want 201 after one", w.Code, *calls)
+	}
+}
+
+func TestIdempotentServerError(t *testing.T) {
+	server, h, calls := idempotentHandler(t, http.StatusServiceUnavailable)
+
+	for i := 0; i < 2; i++ {
+		w := serveIdempotent(h, "key", "", "{}")
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("got %d, want 503", w.Code)
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("the handler ran %d times, want twice", *calls)
+	}
+	if _, ok := server.Get("idempotency:auth0|1:POST:/posts/:key"); ok {
+		t.Error("the key wasn't released")
+	}
+}
+
+func TestIdempotentStoresForTTL(t *testing.T) {
+	server, h, _ := idempotentHandler(t, http.StatusCreated)
+
+	serveIdempotent(h, "key", "", "{}")
+	ttl := server.TTL("idempotency:auth0|1:POST:/posts/:key")
+	if ttl <= 50*time.Second || ttl > time.Minute {
+		t.Errorf("got a TTL of %v, want a minute", ttl)
+	}
+}
+
+func TestIdempotentHoldsKeyBriefly(t *testing.T) {
+	server, client := redistest.NewClient(t)
+	m := &Middleware{Sugar: zap.NewNop().Sugar(), RedisClient: client, IdempotencyTTL: time.Hour}
+	redisKey := "idempotency:auth0|1:POST:/posts/:key"
+
+	var pendingTTL time.Duration
+	h := m.Idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pendingTTL = server.TTL(redisKey)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	serveIdempotent(h, "key", "", "{}")
+
+	// A server dying mid-request doesn't block retries for the whole TTL.
+	if pendingTTL <= 0 || pendingTTL > idempotencyPendingTTL {
+		t.Errorf("got a TTL of %v while processing, want at most %v", pendingTTL, idempotencyPendingTTL)
+	}
+	if ttl := server.TTL(redisKey); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("got a TTL of %v once stored, want an hour", ttl)
+	}
+}
+
+func TestIdempotentKeyTooLong(t *testing.T) {
+	_, h, calls := idempotentHandler(t, http.StatusCreated)
+
+	w := serveIdempotent(h, strings.Repeat("k", maxIdempotencyKeyLength+1), "", "{}")
+	if w.Code != http.StatusBadRequest || *calls != 0 {
+		t.Errorf("got %d after %d calls, want 400 and no call", w.Code, *calls)
+	}
+}
+
+func TestIdempotentRedisDown(t *testing.T) {
+	server, h, calls := idempotentHandler(t, http.StatusCreated)
+	server.SetFailing(true)
+
+	w := serveIdempotent(h, "key", "", "{}")
+	if w.Code != http.StatusInternalServerError || *calls != 0 {
+		t.Errorf("got %d after %d calls, want 500 and no call", w.Code, *calls)
+	}
+}
@@ -2,9 +2,11 @@ package middleware
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	jwtmiddleware "github.com/auth0/go-jwt-middleware/v2"
@@ -22,12 +24,28 @@ import (
 type RequestAuthorCtxKey struct{}
 type PostCtxKey struct{}
 type AuthorCtxKey struct{}
+type TagCtxKey struct{}
+type RevisionCtxKey struct{}
+type TrashedPostCtxKey struct{}
+type PageCtxKey struct{}
+type MediaCtxKey struct{}
 
 type Middleware struct {
 	Sugar       *zap.SugaredLogger
 	Authors     *models.AuthorModel
 	Posts       *models.PostModel
+	Tags        *models.TagModel
+	Revisions   *models.RevisionModel
+	Pages       *models.PageModel
+	Media       *models.MediaModel
 	RedisClient *redis.Client
+
+	PreviewTokens *auth.PreviewTokens
+	// CacheControl is the Cache-Control of public responses of
+	// ConditionalGet.
+	CacheControl string
+	// IdempotencyTTL is how long Idempotent replays responses.
+	IdempotencyTTL time.Duration
 }
 
 func (m *Middleware) AuthorizedRateLimiter(h http.Handler) http.Handler {
@@ -52,19 +70,10 @@ func (m *Middleware) RequiresAuthor(next http.Handler) http.Handler {
 			return
 		}
 
-		// attempt to set Author to context
-		sub := token.RegisteredClaims.Subject
-		author, err := m.Authors.Get(sub)
+		author, err := m.requestAuthor(token)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				// register Author if they are not in the db
-				newAuthor := models.Author{UserId: sub, FullName: claims.Name, Email: claims.Email}
-				m.Authors.Add(&newAuthor)
-				author = &newAuthor
-			} else {
-				render.Render(w, r, resp.ErrInternal(err))
-				panic(err)
-			}
+			RenderError(w, r, err)
+			return
 		}
 		// set Author to the context
 		ctx := context.WithValue(r.Context(), RequestAuthorCtxKey{}, author)
@@ -73,6 +82,54 @@ func (m *Middleware) RequiresAuthor(next http.Handler) http.Handler {
 	})
 }
 
+// OptionalAuthor sets the Author to the context if the request is
+// authenticated as an author, and lets the request through anonymously
+// otherwise. It must come after auth.OptionalValidToken.
+func (m *Middleware) OptionalAuthor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := auth.Claims(r)
+		if !ok || !token.CustomClaims.(*auth.CustomClaims).HasScope("author") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		author, err := m.requestAuthor(token)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		// set Author to the context
+		ctx := context.WithValue(r.Context(), RequestAuthorCtxKey{}, author)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestAuthor returns the Author of the token, registering them if they are
+// not in the db.
+func (m *Middleware) requestAuthor(token *validator.ValidatedClaims) (*models.Author, error) {
+	claims := token.CustomClaims.(*auth.CustomClaims)
+
+	sub := token.RegisteredClaims.Subject
+	author, err := m.Authors.Get(sub)
+	if errors.Is(err, models.ErrNotFound) {
+		newAuthor := models.Author{UserId: sub, FullName: claims.Name, Email: claims.Email}
+		err = m.Authors.Add(&newAuthor)
+		// Registered by a concurrent request in the meantime
+		if errors.Is(err, models.ErrDuplicate) {
+			return m.Authors.Get(sub)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &newAuthor, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return author, nil
+}
+
 // RequiresAuthorOfPost requires the request to be authenticated as the author
 // of the subjected post.
 func (m *Middleware) RequiresAuthorOfPost(next http.Handler) http.Handler {
@@ -101,29 +158,211 @@ func (m *Middleware) RequiresAdmin(next http.Handler) http.Handler {
 	})
 }
 
+// PostContext loads the post of the slug URL parameter. On GET requests, only
+// the fields asked for by the fields query parameter are loaded, along with
+// the authors and the version of the post.
 func (m *Middleware) PostContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var post *models.Post
 		var err error
 
-		if slug := chi.URLParam(r, "slug"); slug != "" {
-			post, err = m.Posts.Get(slug)
+		fields := models.AllPostFields
+		if _, ok := r.URL.Query()["fields"]; ok && r.Method == http.MethodGet {
+			fields, err = models.ParsePostFields(r.URL.Query().Get("fields"))
+			if err != nil {
+				render.Render(w, r, resp.ErrBadRequest(err))
+				return
+			}
+			// RequiresVisiblePost needs the authors, and the ETag the
+			// version, which If-Match is checked against.
+			fields = fields.With("author", "co_authors", "version")
+		}
+		// Rendering to HTML needs the content
+		if r.URL.Query().Get("format") == "html" && r.Method == http.MethodGet {
+			fields = fields.With(models.HTMLPostFields...)
+		}
+
+		slug := chi.URLParam(r, "slug")
+		if slug != "" {
+			post, err = m.Posts.GetFields(slug, fields)
 		} else { // slug empty
 			render.Render(w, r, resp.ErrBadRequest(errors.New("slug required")))
+			return
 		}
-		if err == sql.ErrNoRows {
-			render.Render(w, r, resp.ErrNotFound)
+		if errors.Is(err, models.ErrNotFound) {
+			m.redirectPost(w, r, slug)
 			return
 		}
 		if err != nil {
-			render.Render(w, r, resp.ErrInternal(err))
-			panic(err)
+			RenderError(w, r, err)
+			return
 		}
 		ctx := context.WithValue(r.Context(), PostCtxKey{}, post)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// TrashedPostContext loads the trashed post of the slug URL parameter. The post
+// itself is also put in the context so that RequiresAuthorOfPost can follow.
+func (m *Middleware) TrashedPostContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trashedPost, err := m.Posts.GetTrashed(chi.URLParam(r, "slug"))
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), TrashedPostCtxKey{}, trashedPost)
+		ctx = context.WithValue(ctx, PostCtxKey{}, trashedPost.Post)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// redirectPost permanently redirects requests for a former slug of a post to
+// its current slug, or answers with a 404 if there is no such post.
+func (m *Middleware) redirectPost(w http.ResponseWriter, r *http.Request, oldSlug string) {
+	slug, err := m.Posts.Redirect(oldSlug)
+	if err != nil {
+		RenderError(w, r, err)
+		return
+	}
+
+	segments := strings.Split(r.URL.Path, "/")
+	for i, segment := range segments {
+		if segment == oldSlug {
+			segments[i] = slug
+			break
+		}
+	}
+	location := url.URL{Path: strings.Join(segments, "/"), RawQuery: r.URL.RawQuery}
+
+	// 308 keeps the method and body of requests other than GET.
+	status := http.StatusMovedPermanently
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		status = http.StatusPermanentRedirect
+	}
+	http.Redirect(w, r, location.String(), status)
+}
+
+// RequiresVisiblePost hides drafts and scheduled posts behind a 404, unless
+// the request is authenticated as one of their authors or an admin, or carries
+// a valid preview token of the post. It must come after PostContext.
+func (m *Middleware) RequiresVisiblePost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := r.Context().Value(PostCtxKey{}).(*models.Post)
+
+		if post.IsLive() || auth.IsAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if author, ok := r.Context().Value(RequestAuthorCtxKey{}).(*models.Author); ok && post.IsAuthor(author) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token := r.URL.Query().Get("preview"); token != "" {
+			if err := m.PreviewTokens.Verify(token, post.Slug, time.Now()); err == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		render.Render(w, r, resp.ErrNotFound())
+	})
+}
+
+func (m *Middleware) PageContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var page *models.Page
+		var err error
+
+		if slug := chi.URLParam(r, "slug"); slug != "" {
+			page, err = m.Pages.Get(slug)
+		} else {
+			render.Render(w, r, resp.ErrBadRequest(errors.New("slug required")))
+			return
+		}
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), PageCtxKey{}, page)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequiresVisiblePage hides drafts and scheduled pages behind a 404, unless the
+// request is authenticated as one of their authors or an admin. It must come
+// after PageContext.
+func (m *Middleware) RequiresVisiblePage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.Context().Value(PageCtxKey{}).(*models.Page)
+
+		if page.IsLive() || auth.IsAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if author, ok := r.Context().Value(RequestAuthorCtxKey{}).(*models.Author); ok && page.IsAuthor(author) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		render.Render(w, r, resp.ErrNotFound())
+	})
+}
+
+// RequiresAuthorOfPage requires the request to be authenticated as the author
+// of the subjected page.
+func (m *Middleware) RequiresAuthorOfPage(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.Context().Value(PageCtxKey{}).(*models.Page)
+		author := r.Context().Value(RequestAuthorCtxKey{}).(*models.Author)
+
+		if !page.IsAuthor(author) && !auth.IsAdmin(r) {
+			render.Render(w, r, resp.ErrForbidden(errors.New("you must be the among the authors of the page in order to access this resource")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MediaContext loads the media of the media_id URL parameter.
+func (m *Middleware) MediaContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "media_id"), 10, 64)
+		if err != nil {
+			render.Render(w, r, resp.ErrNotFound())
+			return
+		}
+
+		media, err := m.Media.Get(id)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), MediaCtxKey{}, media)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequiresOwnerOfMedia requires the request to be authenticated as the author
+// who uploaded the subjected media, or as an admin.
+func (m *Middleware) RequiresOwnerOfMedia(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		media := r.Context().Value(MediaCtxKey{}).(*models.Media)
+		author := r.Context().Value(RequestAuthorCtxKey{}).(*models.Author)
+
+		if media.AuthorUserId != author.UserId && !auth.IsAdmin(r) {
+			render.Render(w, r, resp.ErrForbidden(errors.New("you must be the owner of the media in order to access this resource")))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (m *Middleware) AuthorContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var author *models.Author
@@ -133,16 +372,55 @@ func (m *Middleware) AuthorContext(next http.Handler) http.Handler {
 			author, err = m.Authors.Get(userId)
 		} else {
 			render.Render(w, r, resp.ErrBadRequest(errors.New("user_id required")))
-		}
-		if err == sql.ErrNoRows {
-			render.Render(w, r, resp.ErrNotFound)
 			return
 		}
 		if err != nil {
-			render.Render(w, r, resp.ErrInternal(err))
-			panic(err)
+			RenderError(w, r, err)
+			return
 		}
 		ctx := context.WithValue(r.Context(), AuthorCtxKey{}, author)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func (m *Middleware) TagContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tag *models.Tag
+		var err error
+
+		if slug := chi.URLParam(r, "tag"); slug != "" {
+			tag, err = m.Tags.Get(slug)
+		} else {
+			render.Render(w, r, resp.ErrBadRequest(errors.New("tag required")))
+			return
+		}
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), TagCtxKey{}, tag)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RevisionContext loads the revision of the revision_id URL parameter. It must
+// come after PostContext.
+func (m *Middleware) RevisionContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		post := r.Context().Value(PostCtxKey{}).(*models.Post)
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "revision_id"), 10, 64)
+		if err != nil {
+			render.Render(w, r, resp.ErrNotFound())
+			return
+		}
+
+		revision, err := m.Revisions.Get(post.Slug, id)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), RevisionCtxKey{}, revision)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
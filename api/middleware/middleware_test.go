@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// serveWithURLParam serves a request whose URL parameter key is value, as
+// routed by chi.
+func serveWithURLParam(h http.Handler, key, value string) *httptest.ResponseRecorder {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add(key, value)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestContextsRequireURLParam(t *testing.T) {
+	m := &Middleware{}
+	tests := []struct {
+		name       string
+		middleware func(http.Handler) http.Handler
+		key        string
+	}{
+		{"PostContext", m.PostContext, "slug"},
+		{"AuthorContext", m.AuthorContext, "user_id"},
+		{"TagContext", m.TagContext, "tag"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			called := false
+			h := test.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			w := serveWithURLParam(h, test.key, "")
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("got %d, want 400", w.Code)
+			}
+			if called {
+				t.Error("the handler was called without a resource")
+			}
+		})
+	}
+}
@@ -2,79 +2,177 @@
 package resp
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"hxann.com/blog/models"
+	"hxann.com/blog/validation"
 )
 
+// ProblemContentType is the media type of error responses, which are problem
+// details as described by RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Codes of problems. Clients can rely on them not changing.
+const (
+	CodeInternal             = "internal_error"
+	CodeBadRequest           = "bad_request"
+	CodeValidation           = "validation_failed"
+	CodeDuplicate            = "duplicate"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeForbidden            = "forbidden"
+	CodeUnauthorized         = "unauthorized"
+	CodeTooManyRequests      = "too_many_requests"
+	CodeTooLarge             = "too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeConflict             = "conflict"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeUnprocessableEntity  = "unprocessable_entity"
+)
+
+// problemTypePrefix makes the type URI of a problem out of its code.
+const problemTypePrefix = "urn:problem:"
+
+// ErrorResponse is a problem detail. Code is the type of the problem without
+// its URI prefix.
 type ErrorResponse struct {
-	Err     error  `json:"-"`
-	Status  int    `json:"status"`
+	Err       error  `json:"-"`
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestId string `json:"request_id,omitempty"`
+	// Errors lists the invalid fields of validation problems.
+	Errors []FieldError `json:"errors,omitempty"`
+	// Message repeats Detail for clients written before problem details.
 	Message string `json:"message"`
 }
 
+// FieldError is a problem with a field of the request body.
+type FieldError = validation.FieldError
+
+func newProblem(err error, status int, code string, detail string) *ErrorResponse {
+	return &ErrorResponse{
+		Err:     err,
+		Type:    problemTypePrefix + code,
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  detail,
+		Code:    code,
+		Message: detail,
+	}
+}
+
 func (err *ErrorResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	err.Instance = r.URL.Path
+	err.RequestId = middleware.GetReqID(r.Context())
 	render.Status(r, err.Status)
 	return nil
 }
 
-func ErrInternal(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusInternalServerError,
-		Message: "Internal server error.",
+// Respond is a render.Respond that sends error responses as problem details
+// and anything else as render.DefaultResponder does.
+func Respond(w http.ResponseWriter, r *http.Request, v interface{}) {
+	problem, ok := v.(*ErrorResponse)
+	if !ok {
+		render.DefaultResponder(w, r, v)
+		return
 	}
-}
 
-func ErrBadRequest(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusBadRequest,
-		Message: err.Error(),
+	b, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(b)
 }
 
-func ErrDuplicate(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusConflict,
-		Message: "Entity existed.",
+// Problem maps err to the problem it causes: domain errors of models and
+// validation.Errors become client errors, and any other error is internal.
+func Problem(err error) *ErrorResponse {
+	var errs validation.Errors
+	switch {
+	case errors.As(err, &errs):
+		problem := newProblem(err, http.StatusBadRequest, CodeValidation, "Some fields are invalid.")
+		problem.Errors = errs
+		return problem
+	case errors.Is(err, models.ErrStaleVersion):
+		return newProblem(err, http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	case errors.Is(err, models.ErrNotFound):
+		return newProblem(err, http.StatusNotFound, CodeNotFound, err.Error())
+	case errors.Is(err, models.ErrDuplicate):
+		return newProblem(err, http.StatusConflict, CodeDuplicate, err.Error())
+	case errors.Is(err, models.ErrConflict):
+		return newProblem(err, http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, models.ErrForbidden):
+		return newProblem(err, http.StatusForbidden, CodeForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalid):
+		return newProblem(err, http.StatusBadRequest, CodeBadRequest, err.Error())
 	}
+	return newProblem(err, http.StatusInternalServerError, CodeInternal, "Internal server error.")
 }
 
-var ErrNotFound = &ErrorResponse{
-	Status:  http.StatusNotFound,
-	Message: "Resource not found.",
+func ErrInternal(err error) render.Renderer {
+	return newProblem(err, http.StatusInternalServerError, CodeInternal, "Internal server error.")
 }
 
-func ErrNotFoundCustom(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusNotFound,
-		Message: err.Error(),
+// ErrBadRequest renders validation.Errors, like those returned by binding a
+// request body, as ErrValidation.
+func ErrBadRequest(err error) render.Renderer {
+	var errs validation.Errors
+	if errors.As(err, &errs) {
+		return ErrValidation(errs...)
 	}
+	return newProblem(err, http.StatusBadRequest, CodeBadRequest, err.Error())
+}
+
+// ErrValidation reports the invalid fields of the request body.
+func ErrValidation(errs ...FieldError) render.Renderer {
+	problem := newProblem(nil, http.StatusBadRequest, CodeValidation, "Some fields are invalid.")
+	problem.Errors = errs
+	return problem
+}
+
+func ErrNotFound() render.Renderer {
+	return newProblem(nil, http.StatusNotFound, CodeNotFound, "Resource not found.")
+}
+
+func ErrMethodNotAllowed() render.Renderer {
+	return newProblem(nil, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed.")
 }
 
 func ErrForbidden(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusForbidden,
-		Message: err.Error(),
-	}
+	return newProblem(err, http.StatusForbidden, CodeForbidden, err.Error())
 }
 
 func ErrUnauthorized(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusUnauthorized,
-		Message: err.Error(),
-	}
+	return newProblem(err, http.StatusUnauthorized, CodeUnauthorized, err.Error())
 }
 
 func ErrTooManyRequest(err error) render.Renderer {
-	return &ErrorResponse{
-		Err:     err,
-		Status:  http.StatusTooManyRequests,
-		Message: err.Error(),
-	}
+	return newProblem(err, http.StatusTooManyRequests, CodeTooManyRequests, err.Error())
+}
+
+func ErrTooLarge(err error) render.Renderer {
+	return newProblem(err, http.StatusRequestEntityTooLarge, CodeTooLarge, err.Error())
+}
+
+func ErrUnsupportedMediaType(err error) render.Renderer {
+	return newProblem(err, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, err.Error())
+}
+
+func ErrConflict(err error) render.Renderer {
+	return newProblem(err, http.StatusConflict, CodeConflict, err.Error())
+}
+
+func ErrUnprocessableEntity(err error) render.Renderer {
+	return newProblem(err, http.StatusUnprocessableEntity, CodeUnprocessableEntity, err.Error())
 }
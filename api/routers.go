@@ -3,6 +3,10 @@ package api
 import (
 	"database/sql"
 	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -14,47 +18,178 @@ import (
 	"hxann.com/blog/api/handlers"
 	"hxann.com/blog/api/logger"
 	blogMiddleware "hxann.com/blog/api/middleware"
+	"hxann.com/blog/api/resp"
+	"hxann.com/blog/markdown"
 	"hxann.com/blog/models"
+	"hxann.com/blog/storage"
 )
 
-func NewRouter(sugar *zap.SugaredLogger, db *sql.DB, redisClient *redis.Client) *chi.Mux {
+// NewRouter returns the router of the API, storing uploads in blobs. If blobs
+// is localUploads, the router serves its files too.
+func NewRouter(sugar *zap.SugaredLogger, db *sql.DB, redisClient *redis.Client, blobs storage.Storage, localUploads *storage.Local) *chi.Mux {
 	// Initialize some middleware
 	httpLogger := &logger.HTTPLogger{
 		Sugar: sugar,
 	}
 	ensureValidToken := auth.EnsureValidToken(sugar)
+	optionalValidToken := auth.OptionalValidToken(sugar)
+
+	previewSecret := os.Getenv("PREVIEW_SECRET")
+	if previewSecret == "" {
+		sugar.Fatal("$PREVIEW_SECRET must be set")
+	}
+	previewTokens := &auth.PreviewTokens{Secret: []byte(previewSecret)}
+
+	// Renderings are keyed by modification time, so they are never stale and
+	// only expire to free up memory.
+	renderings := &markdown.Cache{Sugar: sugar, Client: redisClient, TTL: 7 * 24 * time.Hour}
+
+	site := handlers.Site{
+		Title:       os.Getenv("SITE_TITLE"),
+		Description: os.Getenv("SITE_DESCRIPTION"),
+		URL:         strings.TrimSuffix(os.Getenv("SITE_URL"), "/"),
+	}
+	if site.URL == "" {
+		sugar.Fatal("$SITE_URL must be set")
+	}
+	if site.Title == "" {
+		site.Title = site.URL
+	}
+	feedSize := 20
+	if s := os.Getenv("FEED_SIZE"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			sugar.Fatal("couldn't parse $FEED_SIZE")
+		}
+		feedSize = n
+	}
+
+	robots := "User-agent: *\nAllow: /\n"
+	if path := os.Getenv("ROBOTS_TXT_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			sugar.Fatalf("couldn't read $ROBOTS_TXT_FILE: %v", err)
+		}
+		robots = string(b)
+	}
+
+	// Responses are revalidated with their ETag on every use by default
+	cacheControl := os.Getenv("CACHE_CONTROL")
+	if cacheControl == "" {
+		cacheControl = "public, no-cache"
+	}
+
+	// Retries of a request with an Idempotency-Key get the same response for
+	// a day by default
+	idempotencyTTL := 24 * time.Hour
+	if s := os.Getenv("IDEMPOTENCY_TTL"); s != "" {
+		var err error
+		idempotencyTTL, err = time.ParseDuration(s)
+		if err != nil {
+			sugar.Fatal("couldn't parse $IDEMPOTENCY_TTL")
+		}
+	}
 
 	postsModel := &models.PostModel{DB: db}
 	authorsModel := &models.AuthorModel{DB: db}
+	tagsModel := &models.TagModel{DB: db}
+	revisionsModel := &models.RevisionModel{DB: db}
+	pagesModel := &models.PageModel{DB: db}
+	mediaModel := &models.MediaModel{DB: db}
+	sitemapModel := &models.SitemapModel{DB: db}
 	middleware := blogMiddleware.Middleware{
 		Sugar:       sugar,
 		Authors:     authorsModel,
 		Posts:       postsModel,
+		Tags:        tagsModel,
+		Revisions:   revisionsModel,
+		Pages:       pagesModel,
+		Media:       mediaModel,
 		RedisClient: redisClient,
+
+		PreviewTokens:  previewTokens,
+		CacheControl:   cacheControl,
+		IdempotencyTTL: idempotencyTTL,
 	}
 
+	// Errors are sent as problem details
+	render.Respond = resp.Respond
+
 	// Create new router
 	r := chi.NewRouter()
+	r.Use(chiMiddleware.RequestID)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "Idempotency-Key", "If-Match", "If-Modified-Since", "If-None-Match"},
+		ExposedHeaders:   []string{"ETag", "Idempotent-Replayed", "Link", "X-Total-Count"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
 	r.Use(httpLogger.LogRequestHandler)
+	// Only bugs panic, as handlers render their errors with RenderError
 	r.Use(chiMiddleware.Recoverer)
+	r.Use(middleware.Errors)
 	r.Use(render.SetContentType(render.ContentTypeJSON))
 
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		render.Render(w, r, resp.ErrNotFound())
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		render.Render(w, r, resp.ErrMethodNotAllowed())
+	})
+
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("hello world"))
 	})
 
-	posts := handlers.NewPosts(postsModel, authorsModel)
+	// Serve uploads stored on the local filesystem
+	if localUploads != nil {
+		r.Handle(localUploads.BaseURL+"/*", http.StripPrefix(localUploads.BaseURL+"/", localUploads.Handler()))
+	}
+
+	// Sitemap of the posts, pages and authors, and robots.txt pointing to it
+	sitemaps := handlers.NewSitemaps(sitemapModel, site, robots)
+	r.Get("/sitemap.xml", sitemaps.SitemapGet)
+	r.Get("/sitemap-{n:[0-9]+}.xml", sitemaps.SitemapPartGet)
+	r.Get("/robots.txt", sitemaps.RobotsGet)
+
+	// Feeds of the latest posts. Tags and authors have their own below.
+	feeds := handlers.NewFeeds(postsModel, renderings, site, feedSize)
+	r.Get("/feed.xml", feeds.RSSGet)
+	r.Get("/atom.xml", feeds.AtomGet)
+	r.Get("/feed.json", feeds.JSONGet)
+
+	posts := handlers.NewPosts(postsModel, authorsModel, previewTokens, blobs, renderings)
+	revisions := handlers.NewRevisions(revisionsModel, posts)
 	r.Route("/posts", func(r chi.Router) {
-		// Unauthenticated endpoints
-		r.Get("/", posts.PostsGet)
-		r.With(middleware.PostContext).Get("/{slug}", posts.PostGet)
+		// Unauthenticated endpoints. Authors also see their drafts.
+		r.With(optionalValidToken, middleware.OptionalAuthor, middleware.ConditionalGet).Get("/", posts.PostsGet)
+		r.Get("/search", posts.PostsSearchGet)
+		r.With(
+			optionalValidToken,
+			middleware.OptionalAuthor,
+			middleware.PostContext,
+			middleware.RequiresVisiblePost,
+			middleware.ConditionalGet,
+		).Get("/{slug}", posts.PostGet)
+
+		// Trashed posts, for their authors and admins
+		r.Route("/trash", func(r chi.Router) {
+			r.Use(ensureValidToken)
+			r.Use(middleware.AuthorizedRateLimiter)
+			r.Use(middleware.RequiresAuthor)
+
+			r.Get("/", posts.TrashGet)
+
+			r.Route("/{slug}", func(r chi.Router) {
+				r.Use(middleware.TrashedPostContext)
+				r.Use(middleware.RequiresAuthorOfPost)
+				r.Get("/", posts.TrashedPostGet)
+				r.Post("/restore", posts.TrashedPostRestorePost)
+				r.Delete("/", posts.TrashedPostDelete)
+			})
+		})
 
 		// Authenticated endpoints for Authors
 		r.Route("/", func(r chi.Router) {
@@ -62,13 +197,101 @@ func NewRouter(sugar *zap.SugaredLogger, db *sql.DB, redisClient *redis.Client)
 			r.Use(middleware.AuthorizedRateLimiter)
 			r.Use(middleware.RequiresAuthor)
 
-			r.Post("/", posts.PostsPost)
+			r.With(middleware.Idempotent).Post("/", posts.PostsPost)
 
 			r.Route("/{slug}", func(r chi.Router) {
 				r.Use(middleware.PostContext)
 				r.Use(middleware.RequiresAuthorOfPost) // requires author to be among the authors of the post
 				r.Put("/", posts.PostPut)
+				r.Patch("/", posts.PostPatch)
 				r.Delete("/", posts.PostDelete)
+				r.Post("/preview", posts.PostPreviewPost)
+				r.Put("/cover_url", posts.PostCoverUrlPut)
+
+				r.Route("/revisions", func(r chi.Router) {
+					r.Get("/", revisions.RevisionsGet)
+
+					r.Route("/{revision_id}", func(r chi.Router) {
+						r.Use(middleware.RevisionContext)
+						r.Get("/", revisions.RevisionGet)
+						r.Get("/diff", revisions.RevisionDiffGet)
+						r.Post("/restore", revisions.RevisionRestorePost)
+					})
+				})
+			})
+		})
+	})
+
+	pages := handlers.NewPages(pagesModel, authorsModel)
+	r.Route("/pages", func(r chi.Router) {
+		// Unauthenticated endpoints. Authors also see their drafts.
+		r.With(optionalValidToken, middleware.OptionalAuthor).Get("/", pages.PagesGet)
+		r.With(
+			optionalValidToken,
+			middleware.OptionalAuthor,
+			middleware.PageContext,
+			middleware.RequiresVisiblePage,
+		).Get("/{slug}", pages.PageGet)
+
+		// Authenticated endpoints for Authors
+		r.Route("/", func(r chi.Router) {
+			r.Use(ensureValidToken)
+			r.Use(middleware.AuthorizedRateLimiter)
+			r.Use(middleware.RequiresAuthor)
+
+			r.Post("/", pages.PagesPost)
+
+			r.Route("/{slug}", func(r chi.Router) {
+				r.Use(middleware.PageContext)
+				r.Use(middleware.RequiresAuthorOfPage)
+				r.Put("/", pages.PagePut)
+				r.Delete("/", pages.PageDelete)
+			})
+		})
+	})
+
+	media := handlers.NewMedia(mediaModel, blobs)
+	r.Route("/media", func(r chi.Router) {
+		r.Use(ensureValidToken)
+		r.Use(middleware.AuthorizedRateLimiter)
+		r.Use(middleware.RequiresAuthor)
+
+		r.Get("/", media.MediaGet)
+		r.Post("/", media.MediaPost)
+
+		r.Route("/{media_id}", func(r chi.Router) {
+			r.Use(middleware.MediaContext)
+			r.Use(middleware.RequiresOwnerOfMedia)
+			r.Get("/", media.MediaItemGet)
+			r.Delete("/", media.MediaItemDelete)
+		})
+	})
+
+	tags := handlers.NewTags(tagsModel)
+	r.Route("/tags", func(r chi.Router) {
+		r.Get("/", tags.TagsGet)
+		r.With(
+			ensureValidToken,
+			middleware.AuthorizedRateLimiter,
+			middleware.RequiresAuthor,
+		).Post("/", tags.TagsPost)
+
+		r.Route("/{tag}", func(r chi.Router) {
+			r.Use(middleware.TagContext)
+			r.Get("/", tags.TagGet)
+			r.With(optionalValidToken, middleware.OptionalAuthor).Get("/posts", posts.PostsOfTagGet)
+			r.Get("/feed.xml", feeds.RSSGet)
+			r.Get("/atom.xml", feeds.AtomGet)
+			r.Get("/feed.json", feeds.JSONGet)
+
+			// Authenticated endpoints for Admins
+			r.Group(func(r chi.Router) {
+				r.Use(ensureValidToken)
+				r.Use(middleware.AuthorizedRateLimiter)
+				r.Use(middleware.RequiresAdmin)
+
+				r.Put("/", tags.TagPut)
+				r.Delete("/", tags.TagDelete)
 			})
 		})
 	})
@@ -82,18 +305,60 @@ func NewRouter(sugar *zap.SugaredLogger, db *sql.DB, redisClient *redis.Client)
 
 			r.Get("/", authors.AuthorsMeGet)
 			r.Put("/", authors.AuthorsMePut)
+			r.Patch("/", authors.AuthorsMePatch)
 		})
 
 		r.Route("/{user_id}", func(r chi.Router) {
 			r.Use(middleware.AuthorContext)
-			r.Get("/", authors.AuthorGet)
-			r.With(
-				ensureValidToken,
-				middleware.AuthorizedRateLimiter,
-				middleware.RequiresAdmin,
-			).Put("/", authors.AuthorPut)
+			r.With(middleware.ConditionalGet).Get("/", authors.AuthorGet)
+			r.Get("/feed.xml", feeds.RSSGet)
+			r.Get("/atom.xml", feeds.AtomGet)
+			r.Get("/feed.json", feeds.JSONGet)
+
+			// Authenticated endpoints for Admins
+			r.Group(func(r chi.Router) {
+				r.Use(ensureValidToken)
+				r.Use(middleware.AuthorizedRateLimiter)
+				r.Use(middleware.RequiresAdmin)
+
+				r.Put("/", authors.AuthorPut)
+				r.Patch("/", authors.AuthorPatch)
+			})
 		})
 	})
 
 	return r
 }
+
+// NewStorage returns the blob storage set by $STORAGE_DRIVER, which is either
+// local (the default) or s3. The local storage is also returned so that the
+// router can serve its files.
+func NewStorage(sugar *zap.SugaredLogger) (storage.Storage, *storage.Local) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		local := &storage.Local{
+			Dir:     os.Getenv("STORAGE_DIR"),
+			BaseURL: "/uploads",
+		}
+		if local.Dir == "" {
+			local.Dir = "uploads"
+		}
+		return local, local
+	case "s3":
+		s3 := &storage.S3{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyId:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}
+		if s3.Endpoint == "" || s3.Region == "" || s3.Bucket == "" {
+			sugar.Fatal("$S3_ENDPOINT, $S3_REGION and $S3_BUCKET must be set")
+		}
+		return s3, nil
+	default:
+		sugar.Fatalf("unknown $STORAGE_DRIVER %q", driver)
+		return nil, nil
+	}
+}
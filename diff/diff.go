@@ -0,0 +1,117 @@
+// Package diff computes line-level differences between two texts.
+package diff
+
+import "strings"
+
+// Op is what happened to a line going from the old text to the new text.
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+// Line is a line of a diff.
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the size of the LCS table, which takes 4 bytes a cell, to
+// 4MB a diff, or about a thousand differing lines on each side. Past it, the
+// differing middle parts of the texts are reported as entirely deleted then
+// inserted.
+const maxCells = 1024 * 1024
+
+// Lines returns the lines of a and b, marked as equal, deleted from a or
+// inserted into b, based on their longest common subsequence.
+func Lines(a, b string) []Line {
+	oldLines := splitLines(a)
+	newLines := splitLines(b)
+
+	// Common prefix and suffix don't need to be part of the LCS table.
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(oldLines)+len(newLines))
+	for _, text := range oldLines[:prefix] {
+		lines = append(lines, Line{Equal, text})
+	}
+	lines = append(lines, middle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	for _, text := range oldLines[len(oldLines)-suffix:] {
+		lines = append(lines, Line{Equal, text})
+	}
+
+	return lines
+}
+
+func middle(oldLines, newLines []string) []Line {
+	n, m := len(oldLines), len(newLines)
+	lines := make([]Line, 0, n+m)
+
+	if (n+1)*(m+1) > maxCells {
+		for _, text := range oldLines {
+			lines = append(lines, Line{Delete, text})
+		}
+		for _, text := range newLines {
+			lines = append(lines, Line{Insert, text})
+		}
+		return lines
+	}
+
+	// lcs[i][j] is the length of the LCS of oldLines[i:] and newLines[j:].
+	lcs := make([][]int32, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			lines = append(lines, Line{Equal, oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Delete, oldLines[i]})
+			i++
+		default:
+			lines = append(lines, Line{Insert, newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		lines = append(lines, Line{Delete, oldLines[i]})
+	}
+	for ; j < m; j++ {
+		lines = append(lines, Line{Insert, newLines[j]})
+	}
+
+	return lines
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
@@ -0,0 +1,90 @@
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{
+			name: "both empty",
+			want: []Line{},
+		},
+		{
+			name: "all inserted",
+			b:    "a\nb",
+			want: []Line{{Insert, "a"}, {Insert, "b"}},
+		},
+		{
+			name: "all deleted",
+			a:    "a\nb",
+			want: []Line{{Delete, "a"}, {Delete, "b"}},
+		},
+		{
+			name: "equal",
+			a:    "a\nb",
+			b:    "a\nb",
+			want: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+		{
+			name: "line changed in the middle",
+			a:    "a\nb\nc",
+			b:    "a\nB\nc",
+			want: []Line{{Equal, "a"}, {Delete, "b"}, {Insert, "B"}, {Equal, "c"}},
+		},
+		{
+			name: "lines moved",
+			a:    "a\nb\nc\nd",
+			b:    "b\nc\na\nd",
+			want: []Line{{Delete, "a"}, {Equal, "b"}, {Equal, "c"}, {Insert, "a"}, {Equal, "d"}},
+		},
+		{
+			name: "CRLF line endings",
+			a:    "a\r\nb",
+			b:    "a\nb",
+			want: []Line{{Equal, "a"}, {Equal, "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinesPastMaxCells(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 1100; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old %d", i))
+		newLines = append(newLines, fmt.Sprintf("new %d", i))
+	}
+	a := "first\n" + strings.Join(oldLines, "\n") + "\nlast"
+	b := "first\n" + strings.Join(newLines, "\n") + "\nlast"
+
+	lines := Lines(a, b)
+	if len(lines) != 2+2*1100 {
+		t.Fatalf("got %d lines, want %d", len(lines), 2+2*1100)
+	}
+	if lines[0] != (Line{Equal, "first"}) || lines[len(lines)-1] != (Line{Equal, "last"}) {
+		t.Errorf("common prefix and suffix aren't equal lines: %v, %v", lines[0], lines[len(lines)-1])
+	}
+	for i, line := range lines[1 : len(lines)-1] {
+		want := Line{Delete, oldLines[i%1100]}
+		if i >= 1100 {
+			want = Line{Insert, newLines[i-1100]}
+		}
+		if line != want {
+			t.Fatalf("line %d = %v, want %v", i+1, line, want)
+		}
+	}
+}
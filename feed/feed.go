@@ -0,0 +1,39 @@
+// Package feed writes syndication feeds in the RSS 2.0, Atom and JSON Feed
+// formats.
+package feed
+
+import "time"
+
+// Feed is a list of items, most recent first, independent of the format it
+// is written in.
+type Feed struct {
+	Title       string
+	Description string
+	// Link is the web page the feed is about, and FeedURL the feed itself.
+	Link    string
+	FeedURL string
+	// Updated is the time the most recent item was updated.
+	Updated time.Time
+	Items   []*Item
+}
+
+type Item struct {
+	// Id uniquely and permanently identifies the item.
+	Id          string
+	Title       string
+	Link        string
+	Summary     string
+	ContentHTML string
+	Authors     []string
+	Tags        []string
+	Published   time.Time
+	Updated     time.Time
+	// Image is the main image of the item, if any.
+	Image *Enclosure
+}
+
+// Enclosure is a file attached to an item.
+type Enclosure struct {
+	URL  string
+	Type string
+}
@@ -0,0 +1,79 @@
+package feed
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+const JSONContentType = "application/feed+json; charset=utf-8"
+
+type jsonFeed struct {
+	Version     string      `json:"version"`
+	Title       string      `json:"title"`
+	HomePageURL string      `json:"home_page_url"`
+	FeedURL     string      `json:"feed_url"`
+	Description string      `json:"description,omitempty"`
+	Items       []*jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	Id            string        `json:"id"`
+	URL           string        `json:"url"`
+	Title         string        `json:"title"`
+	ContentHTML   string        `json:"content_html"`
+	Summary       string        `json:"summary,omitempty"`
+	Image         string        `json:"image,omitempty"`
+	DatePublished string        `json:"date_published,omitempty"`
+	DateModified  string        `json:"date_modified,omitempty"`
+	Authors       []*jsonAuthor `json:"authors,omitempty"`
+	Tags          []string      `json:"tags,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+// WriteJSON writes the feed as JSON Feed 1.1.
+func WriteJSON(w io.Writer, f *Feed) error {
+	doc := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       f.Title,
+		HomePageURL: f.Link,
+		FeedURL:     f.FeedURL,
+		Description: f.Description,
+		Items:       []*jsonItem{},
+	}
+
+	for _, item := range f.Items {
+		jsonItem := &jsonItem{
+			Id:            item.Id,
+			URL:           item.Link,
+			Title:         item.Title,
+			ContentHTML:   item.ContentHTML,
+			Summary:       item.Summary,
+			DatePublished: jsonDate(item.Published),
+			DateModified:  jsonDate(item.Updated),
+			Tags:          item.Tags,
+		}
+		for _, author := range item.Authors {
+			jsonItem.Authors = append(jsonItem.Authors, &jsonAuthor{Name: author})
+		}
+		if item.Image != nil {
+			jsonItem.Image = item.Image.URL
+		}
+		doc.Items = append(doc.Items, jsonItem)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func jsonDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
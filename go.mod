@@ -3,17 +3,19 @@ module hxann.com/blog
 // +heroku goVersion go1.18
 go 1.18
 
-require github.com/auth0/go-jwt-middleware/v2 v2.0.1
+require (
+	github.com/auth0/go-jwt-middleware/v2 v2.0.1
+	github.com/felixge/httpsnoop v1.0.3
+	github.com/pkg/errors v0.9.1
+	go.uber.org/zap v1.21.0
+)
 
 require (
 	github.com/cespare/xxhash/v2 v2.1.2 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
-	github.com/felixge/httpsnoop v1.0.3 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.8.0 // indirect
-	go.uber.org/zap v1.21.0 // indirect
 	gopkg.in/square/go-jose.v2 v2.6.0 // indirect
 )
 
@@ -24,5 +26,6 @@ require (
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/uuid v1.3.0
+	golang.org/x/image v0.18.0
 	golang.org/x/crypto v0.0.0-20220315160706-3147a52a75dd // indirect
 )
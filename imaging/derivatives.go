@@ -0,0 +1,181 @@
+// Package imaging generates the derivatives of uploaded images: resized
+// variants, thumbnails and blur placeholders. It only relies on the standard
+// library, so it runs anywhere Go does.
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"hxann.com/blog/models"
+	"hxann.com/blog/storage"
+)
+
+// ErrUnsupportedImage is returned for content that can't be decoded as a JPEG,
+// PNG or GIF image, or that is too large to be decoded safely.
+var ErrUnsupportedImage = errors.New("imaging: unsupported image")
+
+var (
+	// VariantWidths are the widths of the variants. Only the ones narrower
+	// than the original image are generated.
+	VariantWidths  = []int{320, 640, 1280}
+	ThumbnailWidth = 320
+)
+
+const (
+	placeholderWidth = 16
+	jpegQuality      = 85
+	// maxPixels bounds the memory used to decode an image.
+	maxPixels = 40_000_000
+)
+
+// Generate derives smaller versions of the image in content, stores them in
+// blobs next to the original image stored under key, and returns them. If it
+// fails, the derivatives stored so far are deleted.
+func Generate(ctx context.Context, blobs storage.Storage, key string, content []byte) (*models.ImageDerivatives, error) {
+	recorder := &putRecorder{Storage: blobs}
+	derivatives, err := generate(ctx, recorder, key, content)
+	if err != nil {
+		// The context may be canceled, which is why generating failed. The
+		// error to return is the one that made it fail, whether or not the
+		// derivatives could be deleted.
+		for _, key := range recorder.keys {
+			blobs.Delete(context.Background(), key)
+		}
+		return nil, err
+	}
+	return derivatives, nil
+}
+
+// putRecorder records the keys of the blobs stored through it.
+type putRecorder struct {
+	storage.Storage
+	keys []string
+}
+
+func (s *putRecorder) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	url, err := s.Storage.Put(ctx, key, body, contentType)
+	if err == nil {
+		s.keys = append(s.keys, key)
+	}
+	return url, err
+}
+
+func generate(ctx context.Context, blobs storage.Storage, key string, content []byte) (*models.ImageDerivatives, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || config.Width*config.Height > maxPixels {
+		return nil, ErrUnsupportedImage
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+
+	derivatives := &models.ImageDerivatives{
+		Variants:   []models.ImageVariant{},
+		Thumbnails: map[string]string{},
+	}
+	base := strings.TrimSuffix(key, path.Ext(key))
+
+	for _, width := range VariantWidths {
+		if width >= img.Bounds().Dx() {
+			break
+		}
+		variant := Resize(img, width)
+		url, err := putJPEG(ctx, blobs, variantKey(base, width), variant, jpegQuality)
+		if err != nil {
+			return nil, err
+		}
+		derivatives.Variants = append(derivatives.Variants, models.ImageVariant{
+			Width:  variant.Bounds().Dx(),
+			Height: variant.Bounds().Dy(),
+			Url:    url,
+		})
+	}
+
+	thumbnail := Resize(img, ThumbnailWidth)
+	var jpegThumbnail bytes.Buffer
+	if err := jpeg.Encode(&jpegThumbnail, flatten(thumbnail), &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, err
+	}
+	jpegSize := jpegThumbnail.Len()
+	url, err := blobs.Put(ctx, base+"-thumb.jpg", &jpegThumbnail, "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+	derivatives.Thumbnails["image/jpeg"] = url
+
+	// The WebP encoder is lossless, which only beats JPEG on flat images like
+	// logos and screenshots. For photos, the JPEG is the only thumbnail.
+	var webp bytes.Buffer
+	if err := EncodeWebP(&webp, thumbnail); err != nil {
+		return nil, err
+	}
+	if webp.Len() < jpegSize {
+		url, err = blobs.Put(ctx, base+"-thumb.webp", &webp, "image/webp")
+		if err != nil {
+			return nil, err
+		}
+		derivatives.Thumbnails["image/webp"] = url
+	}
+
+	var placeholder bytes.Buffer
+	if err := jpeg.Encode(&placeholder, flatten(Resize(img, placeholderWidth)), &jpeg.Options{Quality: 50}); err != nil {
+		return nil, err
+	}
+	derivatives.Placeholder = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(placeholder.Bytes())
+
+	return derivatives, nil
+}
+
+// DerivativeKeys returns the keys under which the derivatives of the image
+// stored under key were stored.
+func DerivativeKeys(key string, derivatives *models.ImageDerivatives) []string {
+	if derivatives == nil {
+		return nil
+	}
+
+	base := strings.TrimSuffix(key, path.Ext(key))
+	keys := []string{base + "-thumb.jpg"}
+	if _, ok := derivatives.Thumbnails["image/webp"]; ok {
+		keys = append(keys, base+"-thumb.webp")
+	}
+	for _, variant := range derivatives.Variants {
+		keys = append(keys, variantKey(base, variant.Width))
+	}
+	return keys
+}
+
+// Delete deletes the image stored under key from blobs, along with its
+// derivatives. The image is deleted last, so that a failure leaves orphan
+// derivatives rather than derivatives of a missing image.
+func Delete(ctx context.Context, blobs storage.Storage, key string, derivatives *models.ImageDerivatives) error {
+	for _, key := range append(DerivativeKeys(key, derivatives), key) {
+		if err := blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func variantKey(base string, width int) string {
+	return base + "-" + strconv.Itoa(width) + "w.jpg"
+}
+
+func putJPEG(ctx context.Context, blobs storage.Storage, key string, img image.Image, quality int) (string, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: quality}); err != nil {
+		return "", err
+	}
+	return blobs.Put(ctx, key, &buf, "image/jpeg")
+}
@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memoryStorage stores blobs in memory, at URLs of the form memory://key.
+type memoryStorage map[string][]byte
+
+func (s memoryStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	s[key] = b
+	return "memory://" + key, nil
+}
+
+func (s memoryStorage) Delete(ctx context.Context, key string) error {
+	delete(s, key)
+	return nil
+}
+
+func (s memoryStorage) keys() []string {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestGenerate(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name   string
+		img    image.Image
+		webp   bool
+		widths []int
+	}{
+		{
+			// Flat images, like logos, compress better losslessly.
+			name: "flat",
+			img: fill(700, 400, func(x, y int) color.NRGBA {
+				if (x/100+y/100)%2 == 0 {
+					return color.NRGBA{R: 0x20, G: 0x40, B: 0x80, A: 0xff}
+				}
+				return color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}),
+			webp:   true,
+			widths: []int{320, 640},
+		},
+		{
+			// Noise, like the grain of photos, doesn't.
+			name: "noisy",
+			img: fill(500, 300, func(x, y int) color.NRGBA {
+				return color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: 0xff}
+			}),
+			webp:   false,
+			widths: []int{320},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var content bytes.Buffer
+			if err := png.Encode(&content, test.img); err != nil {
+				t.Fatal(err)
+			}
+			blobs := memoryStorage{}
+			key := "covers/" + test.name + ".png"
+			blobs[key] = content.Bytes()
+
+			derivatives, err := Generate(context.Background(), blobs, key, content.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var widths []int
+			for _, variant := range derivatives.Variants {
+				widths = append(widths, variant.Width)
+				if _, ok := blobs[strings.TrimPrefix(variant.Url, "memory://")]; !ok {
+					t.Errorf("variant %s is not stored", variant.Url)
+				}
+			}
+			if len(widths) != len(test.widths) || (len(widths) > 0 && widths[len(widths)-1] != test.widths[len(test.widths)-1]) {
+				t.Errorf("got variants of widths %v, want %v", widths, test.widths)
+			}
+
+			jpegUrl, ok := derivatives.Thumbnails["image/jpeg"]
+			if !ok {
+				t.Fatal("no JPEG thumbnail")
+			}
+			webpUrl, ok := derivatives.Thumbnails["image/webp"]
+			if ok != test.webp {
+				t.Fatalf("got a WebP thumbnail %v, want %v", ok, test.webp)
+			}
+			if ok {
+				jpegSize := len(blobs[strings.TrimPrefix(jpegUrl, "memory://")])
+				webpSize := len(blobs[strings.TrimPrefix(webpUrl, "memory://")])
+				if webpSize >= jpegSize {
+					t.Errorf("got a WebP thumbnail of %d bytes, larger than the JPEG one of %d bytes", webpSize, jpegSize)
+				}
+			}
+			if !strings.HasPrefix(derivatives.Placeholder, "data:image/jpeg;base64,") {
+				t.Errorf("got placeholder %.40s..., want a JPEG data URI", derivatives.Placeholder)
+			}
+
+			stored := blobs.keys()
+			keys := append(DerivativeKeys(key, derivatives), key)
+			sort.Strings(keys)
+			if strings.Join(keys, " ") != strings.Join(stored, " ") {
+				t.Errorf("got derivative keys %v, want the stored keys %v", keys, stored)
+			}
+
+			if err := Delete(context.Background(), blobs, key, derivatives); err != nil {
+				t.Fatal(err)
+			}
+			if len(blobs) != 0 {
+				t.Errorf("blobs left after deleting: %v", blobs.keys())
+			}
+		})
+	}
+}
+
+func TestGenerateRejectsUnsupportedImages(t *testing.T) {
+	for name, content := range map[string][]byte{
+		"text":  []byte("not an image"),
+		"empty": nil,
+	} {
+		_, err := Generate(context.Background(), memoryStorage{}, "covers/x.png", content)
+		if err != ErrUnsupportedImage {
+			t.Errorf("%s: got %v, want ErrUnsupportedImage", name, err)
+		}
+	}
+}
+
+// failingStorage fails to store the blob under key.
+type failingStorage struct {
+	memoryStorage
+	key string
+}
+
+func (s failingStorage) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	if key == s.key {
+		return "", errors.New("storage failed")
+	}
+	return s.memoryStorage.Put(ctx, key, body, contentType)
+}
+
+func TestGenerateDeletesDerivativesOnFailure(t *testing.T) {
+	var content bytes.Buffer
+	if err := png.Encode(&content, fill(700, 400, func(x, y int) color.NRGBA {
+		return color.NRGBA{A: 0xff}
+	})); err != nil {
+		t.Fatal(err)
+	}
+	blobs := failingStorage{memoryStorage: memoryStorage{}, key: "covers/x-thumb.jpg"}
+	blobs.memoryStorage["covers/x.png"] = content.Bytes()
+
+	if _, err := Generate(context.Background(), blobs, "covers/x.png", content.Bytes()); err == nil {
+		t.Fatal("got no error")
+	}
+	if keys := blobs.keys(); len(keys) != 1 || keys[0] != "covers/x.png" {
+		t.Errorf("got blobs %v, want only the original image", keys)
+	}
+}
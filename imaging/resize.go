@@ -0,0 +1,122 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Resize scales img down to the given width, keeping its aspect ratio. Every
+// pixel of the result is the average of the area of img it covers, which keeps
+// downscaled photos smooth. img is returned as is if it isn't wider than
+// width.
+func Resize(img image.Image, width int) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() <= width {
+		return img
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	return resizeVertically(resizeHorizontally(src, width), height)
+}
+
+// span is a source pixel contributing to a destination pixel.
+type span struct {
+	index  int
+	weight float64
+}
+
+// spans returns, for every destination pixel, the source pixels that it covers
+// when scaling srcLen pixels down to dstLen pixels.
+func spans(srcLen, dstLen int) [][]span {
+	scale := float64(srcLen) / float64(dstLen)
+	result := make([][]span, dstLen)
+	for i := range result {
+		start := float64(i) * scale
+		end := start + scale
+		for j := int(start); j < srcLen && float64(j) < end; j++ {
+			from, to := float64(j), float64(j+1)
+			if from < start {
+				from = start
+			}
+			if to > end {
+				to = end
+			}
+			result[i] = append(result[i], span{j, (to - from) / scale})
+		}
+	}
+	return result
+}
+
+func resizeHorizontally(src *image.RGBA, width int) *image.RGBA {
+	height := src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	columns := spans(src.Bounds().Dx(), width)
+
+	for y := 0; y < height; y++ {
+		for x, column := range columns {
+			var r, g, b, a float64
+			for _, s := range column {
+				c := src.RGBAAt(s.index, y)
+				r += float64(c.R) * s.weight
+				g += float64(c.G) * s.weight
+				b += float64(c.B) * s.weight
+				a += float64(c.A) * s.weight
+			}
+			dst.SetRGBA(x, y, rgba(r, g, b, a))
+		}
+	}
+	return dst
+}
+
+func resizeVertically(src *image.RGBA, height int) *image.RGBA {
+	width := src.Bounds().Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	rows := spans(src.Bounds().Dy(), height)
+
+	for y, row := range rows {
+		for x := 0; x < width; x++ {
+			var r, g, b, a float64
+			for _, s := range row {
+				c := src.RGBAAt(x, s.index)
+				r += float64(c.R) * s.weight
+				g += float64(c.G) * s.weight
+				b += float64(c.B) * s.weight
+				a += float64(c.A) * s.weight
+			}
+			dst.SetRGBA(x, y, rgba(r, g, b, a))
+		}
+	}
+	return dst
+}
+
+// rgba rounds premultiplied channel averages back to a color.
+func rgba(r, g, b, a float64) color.RGBA {
+	return color.RGBA{clamp(r), clamp(g), clamp(b), clamp(a)}
+}
+
+func clamp(v float64) uint8 {
+	v += 0.5
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
+
+// flatten draws img over a white background, as JPEG has no transparency.
+func flatten(img image.Image) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+	return dst
+}
@@ -0,0 +1,485 @@
+package imaging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// maxWebPDimension is the largest width or height of a lossless WebP image.
+const maxWebPDimension = 1 << 14
+
+var errWebPTooLarge = errors.New("imaging: image too large for WebP")
+
+// EncodeWebP writes img to w as a lossless WebP image.
+//
+// It is a minimal VP8L encoder: pixels go through the subtract green and
+// select predictor transforms, then are entropy coded with one Huffman code
+// per channel. The only backward references are runs repeating the previous
+// pixel, which flat areas predict to. That is enough for thumbnails of logos
+// and screenshots without any cgo dependency, but photos are larger than as
+// JPEG.
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > maxWebPDimension || height > maxWebPDimension {
+		return errWebPTooLarge
+	}
+
+	// Subtract green: red and blue are stored as their difference with green,
+	// which makes their histograms much narrower for natural images.
+	pixels := make([][4]uint8, 0, width*height) // green, red, blue, alpha
+	hasAlpha := false
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A != 0xff {
+				hasAlpha = true
+			}
+			pixels = append(pixels, [4]uint8{c.G, c.R - c.G, c.B - c.G, c.A})
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8) // VP8L signature
+	bw.write(uint64(width-1), 14)
+	bw.write(uint64(height-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+
+	bw.write(1, 1) // a transform follows
+	bw.write(2, 2) // subtract green
+
+	bw.write(1, 1) // a transform follows
+	bw.write(0, 2) // predictor
+	pixels = predict(pixels, width)
+	// A single block covers up to 512 by 512 pixels, each block using the
+	// select predictor, stored in the green channel of the block image.
+	bw.write(predictorBlockBits-2, 3)
+	bw.write(0, 1) // no color cache
+	writeSimpleCode(bw, []int{selectPredictor})
+	for i := 0; i < 4; i++ {
+		writeSimpleCode(bw, []int{0})
+	}
+
+	bw.write(0, 1) // no more transforms
+
+	bw.write(0, 1) // no color cache
+	bw.write(0, 1) // a single group of prefix codes
+
+	// Green symbols from 256 on are the prefixes of the lengths of backward
+	// references.
+	tokens := runs(pixels)
+	histograms := [4][]int{make([]int, 256+24), make([]int, 256), make([]int, 256), make([]int, 256)}
+	for _, token := range tokens {
+		if token.length > 0 {
+			symbol, _, _ := lz77Prefix(token.length)
+			histograms[0][256+symbol]++
+			continue
+		}
+		for channel, histogram := range histograms {
+			histogram[token.pixel[channel]]++
+		}
+	}
+	var codes [4]prefixCode
+	for channel, histogram := range histograms {
+		codes[channel] = newPrefixCode(histogram, 15)
+		codes[channel].writeTo(bw)
+	}
+	// Every backward reference is to the previous pixel, so the distance code
+	// has a single symbol, which takes no bits.
+	distanceSymbol, _, _ := lz77Prefix(previousPixelDistanceCode)
+	writeSimpleCode(bw, []int{distanceSymbol})
+
+	for _, token := range tokens {
+		if token.length > 0 {
+			symbol, extraBits, extra := lz77Prefix(token.length)
+			codes[0].writeSymbol(bw, 256+symbol)
+			bw.write(uint64(extra), extraBits)
+			continue
+		}
+		for channel, code := range codes {
+			code.writeSymbol(bw, int(token.pixel[channel]))
+		}
+	}
+
+	return writeRIFF(w, bw.bytes())
+}
+
+const (
+	// minRun is the shortest run coded as a backward reference rather than
+	// literal pixels.
+	minRun = 2
+	maxRun = 4096
+	// previousPixelDistanceCode is the distance code of the pixel to the
+	// left, the second of the codes for the neighbourhood of a pixel.
+	previousPixelDistanceCode = 2
+)
+
+// token is a literal pixel, or a backward reference repeating the previous
+// pixel length times.
+type token struct {
+	pixel  [4]uint8
+	length int
+}
+
+// runs splits pixels into literals and runs of the previous pixel.
+func runs(pixels [][4]uint8) []token {
+	tokens := make([]token, 0, len(pixels))
+	for i := 0; i < len(pixels); {
+		length := 0
+		for i > 0 && i+length < len(pixels) && length < maxRun && pixels[i+length] == pixels[i-1] {
+			length++
+		}
+		if length >= minRun {
+			tokens = append(tokens, token{length: length})
+			i += length
+			continue
+		}
+		tokens = append(tokens, token{pixel: pixels[i]})
+		i++
+	}
+	return tokens
+}
+
+// lz77Prefix returns the prefix symbol of value, a length or distance code of
+// a backward reference, and the extra bits that follow it.
+func lz77Prefix(value int) (symbol int, extraBits uint, extra int) {
+	value--
+	if value < 4 {
+		return value, 0, 0
+	}
+	highest := bits.Len(uint(value)) - 1
+	second := value >> (highest - 1) & 1
+	extraBits = uint(highest - 1)
+	return 2*highest + second, extraBits, value & (1<<extraBits - 1)
+}
+
+const (
+	predictorBlockBits = 9
+	selectPredictor    = 11
+)
+
+// predict replaces pixels, in green, red, blue, alpha order, with their
+// difference with the prediction of the select predictor. The first row is
+// predicted from the left pixel and the first column from the top pixel.
+func predict(pixels [][4]uint8, width int) [][4]uint8 {
+	residuals := make([][4]uint8, len(pixels))
+	for i, p := range pixels {
+		var prediction [4]uint8
+		x, y := i%width, i/width
+		switch {
+		case x == 0 && y == 0:
+			prediction = [4]uint8{0, 0, 0, 0xff} // opaque black
+		case y == 0:
+			prediction = pixels[i-1]
+		case x == 0:
+			prediction = pixels[i-width]
+		default:
+			prediction = selectPrediction(pixels[i-1], pixels[i-width], pixels[i-width-1])
+		}
+		for c := range p {
+			residuals[i][c] = p[c] - prediction[c]
+		}
+	}
+	return residuals
+}
+
+// selectPrediction returns whichever of the left and top pixels is closer to
+// the gradient estimate left + top - topLeft.
+func selectPrediction(left, top, topLeft [4]uint8) [4]uint8 {
+	distanceToLeft, distanceToTop := 0, 0
+	for c := range left {
+		distanceToLeft += abs(int(top[c]) - int(topLeft[c]))
+		distanceToTop += abs(int(left[c]) - int(topLeft[c]))
+	}
+	if distanceToLeft < distanceToTop {
+		return left
+	}
+	return top
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func writeRIFF(w io.Writer, data []byte) error {
+	padding := len(data) & 1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(data)+padding))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+
+	bw := bufio.NewWriter(w)
+	bw.Write(header)
+	bw.Write(data)
+	if padding == 1 {
+		bw.WriteByte(0)
+	}
+	return bw.Flush()
+}
+
+// bitWriter packs bits least significant bit first, as VP8L expects.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nbits uint
+}
+
+func (w *bitWriter) write(bits uint64, n uint) {
+	w.acc |= bits << w.nbits
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nbits -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.nbits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nbits = 0, 0
+	}
+	return w.buf
+}
+
+// prefixCode is a canonical Huffman code, or a simple code of at most two
+// symbols below 256.
+type prefixCode struct {
+	simple  []int
+	lengths []int
+	// codes are bit-reversed, so that they can be written least significant
+	// bit first.
+	codes []uint64
+}
+
+func newPrefixCode(histogram []int, maxLength int) prefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		return prefixCode{simple: used}
+	}
+
+	lengths := huffmanLengths(histogram, maxLength)
+	return prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+func (c prefixCode) writeTo(w *bitWriter) {
+	if c.simple != nil {
+		writeSimpleCode(w, c.simple)
+		return
+	}
+
+	w.write(0, 1) // normal code
+
+	// The code lengths are themselves Huffman coded, with symbols 0 to 15
+	// being literal lengths. Repeat codes 16 to 18 aren't used.
+	histogram := make([]int, 19)
+	for _, length := range c.lengths {
+		histogram[length]++
+	}
+	lengthCode := newLengthCode(histogram)
+
+	order := [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	w.write(19-4, 4)
+	for _, symbol := range order {
+		w.write(uint64(lengthCode.lengths[symbol]), 3)
+	}
+
+	w.write(0, 1) // every symbol of the alphabet has a length
+	for _, length := range c.lengths {
+		lengthCode.writeSymbol(w, length)
+	}
+}
+
+// newLengthCode returns the code of code lengths. Unlike other codes, it is
+// always a normal code, so it needs at least two symbols.
+func newLengthCode(histogram []int) prefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 1 {
+		if used[0] == 0 {
+			histogram[1] = 1
+		} else {
+			histogram[0] = 1
+		}
+	}
+
+	lengths := huffmanLengths(histogram, 7)
+	return prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+func (c prefixCode) writeSymbol(w *bitWriter, symbol int) {
+	if c.simple != nil {
+		if len(c.simple) == 2 && symbol == c.simple[1] {
+			w.write(1, 1)
+		} else if len(c.simple) == 2 {
+			w.write(0, 1)
+		}
+		return
+	}
+	w.write(c.codes[symbol], uint(c.lengths[symbol]))
+}
+
+func writeSimpleCode(w *bitWriter, symbols []int) {
+	w.write(1, 1) // simple code
+	w.write(uint64(len(symbols)-1), 1)
+	if symbols[0] < 2 {
+		w.write(0, 1)
+		w.write(uint64(symbols[0]), 1)
+	} else {
+		w.write(1, 1)
+		w.write(uint64(symbols[0]), 8)
+	}
+	if len(symbols) == 2 {
+		w.write(uint64(symbols[1]), 8)
+	}
+}
+
+// huffmanLengths returns the Huffman code lengths of the symbols of histogram,
+// none longer than maxLength. Unused symbols have a length of 0.
+func huffmanLengths(histogram []int, maxLength int) []int {
+	counts := append([]int(nil), histogram...)
+	for {
+		lengths, longest := huffmanLengthsUnbounded(counts)
+		if longest <= maxLength {
+			return lengths
+		}
+		// Flatten the distribution until the code is short enough.
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = count/2 + 1
+			}
+		}
+	}
+}
+
+func huffmanLengthsUnbounded(counts []int) ([]int, int) {
+	type node struct {
+		count       int
+		symbol      int // -1 for internal nodes
+		left, right *node
+	}
+
+	var nodes []*node
+	for symbol, count := range counts {
+		if count > 0 {
+			nodes = append(nodes, &node{count: count, symbol: symbol})
+		}
+	}
+
+	lengths := make([]int, len(counts))
+	if len(nodes) == 1 {
+		lengths[nodes[0].symbol] = 1
+		return lengths, 1
+	}
+
+	// A sorted queue of leaves and a queue of internal nodes, which are
+	// created in increasing count order, merge in linear time.
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].count != nodes[j].count {
+			return nodes[i].count < nodes[j].count
+		}
+		return nodes[i].symbol < nodes[j].symbol
+	})
+	var internal []*node
+	pop := func() *node {
+		if len(internal) == 0 || (len(nodes) > 0 && nodes[0].count <= internal[0].count) {
+			n := nodes[0]
+			nodes = nodes[1:]
+			return n
+		}
+		n := internal[0]
+		internal = internal[1:]
+		return n
+	}
+	for len(nodes)+len(internal) > 1 {
+		a, b := pop(), pop()
+		internal = append(internal, &node{count: a.count + b.count, symbol: -1, left: a, right: b})
+	}
+
+	longest := 0
+	var walk func(n *node, depth int)
+	walk = func(n *node, depth int) {
+		if n.symbol >= 0 {
+			lengths[n.symbol] = depth
+			if depth > longest {
+				longest = depth
+			}
+			return
+		}
+		walk(n.left, depth+1)
+		walk(n.right, depth+1)
+	}
+	walk(internal[0], 0)
+
+	return lengths, longest
+}
+
+// canonicalCodes assigns the canonical Huffman codes of lengths, as in
+// DEFLATE, and returns them bit-reversed.
+func canonicalCodes(lengths []int) []uint64 {
+	maxLength := 0
+	for _, length := range lengths {
+		if length > maxLength {
+			maxLength = length
+		}
+	}
+
+	lengthCounts := make([]int, maxLength+1)
+	for _, length := range lengths {
+		if length > 0 {
+			lengthCounts[length]++
+		}
+	}
+	nextCode := make([]uint64, maxLength+2)
+	code := uint64(0)
+	for length := 1; length <= maxLength; length++ {
+		code = (code + uint64(lengthCounts[length-1])) << 1
+		nextCode[length] = code
+	}
+
+	codes := make([]uint64, len(lengths))
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		codes[symbol] = reverseBits(nextCode[length], length)
+		nextCode[length]++
+	}
+	return codes
+}
+
+func reverseBits(code uint64, length int) uint64 {
+	var reversed uint64
+	for i := 0; i < length; i++ {
+		reversed = reversed<<1 | code&1
+		code >>= 1
+	}
+	return reversed
+}
@@ -0,0 +1,100 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeWebP(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+
+	tests := map[string]image.Image{
+		"1x1":   solid(1, 1, color.NRGBA{R: 10, G: 20, B: 30, A: 0xff}),
+		"solid": solid(40, 30, color.NRGBA{R: 200, G: 100, B: 50, A: 0xff}),
+		// Runs longer than a backward reference.
+		"long runs": fill(150, 100, func(x, y int) color.NRGBA {
+			if y < 90 {
+				return color.NRGBA{R: 255, G: 255, B: 255, A: 0xff}
+			}
+			return color.NRGBA{R: uint8(x), A: 0xff}
+		}),
+		"gradient": fill(64, 48, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 4), G: uint8(y * 5), B: uint8(x + y), A: 0xff}
+		}),
+		"alpha": fill(33, 17, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x * 7), G: 0x80, B: uint8(y * 13), A: uint8(x * y)}
+		}),
+		"noise": fill(50, 40, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(random.Intn(256)), G: uint8(random.Intn(256)), B: uint8(random.Intn(256)), A: uint8(random.Intn(256))}
+		}),
+		// Wider than a predictor block, and not at the origin.
+		"wide": fill(1100, 3, func(x, y int) color.NRGBA {
+			return color.NRGBA{R: uint8(x), G: uint8(x >> 2), B: uint8(y * 90), A: 0xff}
+		}).SubImage(image.Rect(5, 1, 1100, 3)),
+		"paletted": func() image.Image {
+			img := image.NewPaletted(image.Rect(0, 0, 20, 20), []color.Color{color.Black, color.White})
+			for i := range img.Pix {
+				img.Pix[i] = uint8(i % 3 % 2)
+			}
+			return img
+		}(),
+	}
+
+	for name, img := range tests {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, img); err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bounds := img.Bounds()
+			if decoded.Bounds().Dx() != bounds.Dx() || decoded.Bounds().Dy() != bounds.Dy() {
+				t.Fatalf("got %v, want %dx%d", decoded.Bounds(), bounds.Dx(), bounds.Dy())
+			}
+			for y := 0; y < bounds.Dy(); y++ {
+				for x := 0; x < bounds.Dx(); x++ {
+					want := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y))
+					got := color.NRGBAModel.Convert(decoded.At(decoded.Bounds().Min.X+x, decoded.Bounds().Min.Y+y))
+					if got != want {
+						t.Fatalf("pixel (%d, %d): got %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPRejectsSizes(t *testing.T) {
+	for _, rect := range []image.Rectangle{
+		image.Rect(0, 0, 0, 10),
+		image.Rect(0, 0, maxWebPDimension+1, 1),
+		image.Rect(0, 0, 1, maxWebPDimension+1),
+	} {
+		if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(rect)); err != errWebPTooLarge {
+			t.Errorf("%v: got %v, want errWebPTooLarge", rect, err)
+		}
+	}
+}
+
+func solid(width, height int, c color.NRGBA) *image.NRGBA {
+	return fill(width, height, func(x, y int) color.NRGBA { return c })
+}
+
+func fill(width, height int, at func(x, y int) color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, at(x, y))
+		}
+	}
+	return img
+}
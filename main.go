@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	_ "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 	"hxann.com/blog/api"
+	"hxann.com/blog/models"
+	"hxann.com/blog/publisher"
+	"hxann.com/blog/trash"
 )
 
 func main() {
@@ -45,8 +50,45 @@ func main() {
 	}
 	redisClient := redis.NewClient(redisOpt)
 
+	// Start announcing scheduled posts in the background
+	publisherInterval := 30 * time.Second
+	if s := os.Getenv("PUBLISHER_INTERVAL"); s != "" {
+		publisherInterval, err = time.ParseDuration(s)
+		if err != nil {
+			sugar.Fatal("couldn't parse $PUBLISHER_INTERVAL")
+		}
+	}
+	postPublisher := &publisher.Publisher{
+		Sugar:       sugar,
+		Posts:       &models.PostModel{DB: db},
+		RedisClient: redisClient,
+		Interval:    publisherInterval,
+		Hooks:       []publisher.Hook{publisher.LogHook(sugar)},
+	}
+	go postPublisher.Run(context.Background())
+
+	// Start purging old trashed posts in the background
+	trashRetention := 30 * 24 * time.Hour
+	if s := os.Getenv("TRASH_RETENTION"); s != "" {
+		trashRetention, err = time.ParseDuration(s)
+		if err != nil {
+			sugar.Fatal("couldn't parse $TRASH_RETENTION")
+		}
+	}
+	blobs, localUploads := api.NewStorage(sugar)
+
+	trashPurger := &trash.Purger{
+		Sugar:       sugar,
+		Posts:       &models.PostModel{DB: db},
+		Blobs:       blobs,
+		RedisClient: redisClient,
+		Retention:   trashRetention,
+		Interval:    time.Hour,
+	}
+	go trashPurger.Run(context.Background())
+
 	// Create router
-	r := api.NewRouter(sugar, db, redisClient)
+	r := api.NewRouter(sugar, db, redisClient, blobs, localUploads)
 
 	sugar.Info("Server started on port " + port)
 	http.ListenAndServe(":"+port, r)
@@ -0,0 +1,489 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	atxHeadingRe    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicBreakRe = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	fenceRe         = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	blockquoteRe    = regexp.MustCompile(`^ {0,3}> ?`)
+	setextRe        = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	tableDelimRe    = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	mdxStatementRe  = regexp.MustCompile(`^(import|export)\s`)
+	jsxBlockRe      = regexp.MustCompile(`^ {0,3}<(/?[A-Z][\w.]*|>)`)
+)
+
+// maxNesting is the deepest that lists and blockquotes nest. Deeper markers
+// are text, as every level parses the lines of the levels below it again.
+const maxNesting = 32
+
+// parser renders blocks and collects the table of contents and word count of
+// the document.
+type parser struct {
+	doc *Document
+	// ids counts the headings with a given id, to make them unique.
+	ids map[string]int
+	// refs are the link reference definitions of the document, which
+	// paragraphs add to as they start with them.
+	refs linkReferences
+	// depth is the number of lists and blockquotes around the blocks being
+	// parsed.
+	depth int
+}
+
+// blocks renders lines as a sequence of blocks. In tight lists, paragraphs are
+// not wrapped in <p>.
+func (p *parser) blocks(lines []string, out *strings.Builder, tight bool) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case isBlank(line):
+			i++
+
+		case fenceRe.MatchString(line):
+			i = p.fencedCode(lines, i, out)
+
+		case atxHeadingRe.MatchString(line):
+			m := atxHeadingRe.FindStringSubmatch(line)
+			p.heading(len(m[1]), m[2], out)
+			i++
+
+		case isThematicBreak(line):
+			out.WriteString("<hr />\n")
+			i++
+
+		case blockquoteRe.MatchString(line) && p.depth < maxNesting:
+			i = p.blockquote(lines, i, out)
+
+		case isListItem(line) && p.depth < maxNesting:
+			i = p.list(lines, i, out)
+
+		case indentation(line) >= 4:
+			i = indentedCode(lines, i, out)
+
+		case mdxStatementRe.MatchString(line), jsxBlockRe.MatchString(line):
+			// MDX statements and components have no server-side rendering.
+			i = skipUntilBlank(lines, i)
+
+		case strings.HasPrefix(strings.TrimSpace(line), "<!--"):
+			i = skipComment(lines, i)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableDelimRe.MatchString(lines[i+1]):
+			i = p.table(lines, i, out)
+
+		default:
+			i = p.paragraph(lines, i, out, tight)
+		}
+	}
+}
+
+func (p *parser) heading(level int, text string, out *strings.Builder) {
+	content := renderInline(strings.TrimSpace(text), p.refs)
+	plain := plainText(content)
+	id := p.headingId(plain)
+
+	p.doc.TOC = append(p.doc.TOC, Heading{Level: level, Id: id, Text: plain})
+	p.countWords(plain)
+
+	tag := "h" + strconv.Itoa(level)
+	out.WriteString("<" + tag + ` id="` + html.EscapeString(id) + `">` + content + "</" + tag + ">\n")
+}
+
+func (p *parser) fencedCode(lines []string, i int, out *strings.Builder) int {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, fence, info := len(m[1]), m[2], m[3]
+
+	var code strings.Builder
+	i++
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if indentation(line) < 4 && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code.WriteString(trimIndent(line, indent) + "\n")
+	}
+
+	out.WriteString("<pre><code")
+	if language := strings.Fields(info); len(language) > 0 {
+		out.WriteString(` class="language-` + html.EscapeString(language[0]) + `"`)
+	}
+	out.WriteString(">" + html.EscapeString(code.String()) + "</code></pre>\n")
+	return i
+}
+
+func indentedCode(lines []string, i int, out *strings.Builder) int {
+	var code []string
+	for ; i < len(lines) && (isBlank(lines[i]) || indentation(lines[i]) >= 4); i++ {
+		code = append(code, trimIndent(lines[i], 4))
+	}
+	for len(code) > 0 && isBlank(code[len(code)-1]) {
+		code = code[:len(code)-1]
+	}
+
+	out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")+"\n") + "</code></pre>\n")
+	return i
+}
+
+func (p *parser) blockquote(lines []string, i int, out *strings.Builder) int {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if loc := blockquoteRe.FindStringIndex(line); loc != nil {
+			inner = append(inner, line[loc[1]:])
+			continue
+		}
+		// Lazy continuation of a paragraph of the quote
+		if isBlank(line) || len(inner) == 0 || isBlank(inner[len(inner)-1]) || interruptsParagraph(line) {
+			break
+		}
+		inner = append(inner, line)
+	}
+
+	out.WriteString("<blockquote>\n")
+	p.depth++
+	p.blocks(inner, out, false)
+	p.depth--
+	out.WriteString("</blockquote>\n")
+	return i
+}
+
+// listMarker is the marker of a list item, e.g. "-" or "1.".
+type listMarker struct {
+	ordered bool
+	// delimiter is the bullet of unordered lists, or the character following
+	// the number of ordered lists.
+	delimiter byte
+	start     int
+	// contentIndent is the column where the content of the item starts.
+	contentIndent int
+	rest          string
+}
+
+// parseListMarker parses the marker starting line: up to 3 spaces, a bullet
+// or a number of up to 9 digits followed by . or ), and spaces or the end of
+// the line. It only scans the marker, as lines can be as long as the content.
+func parseListMarker(line string) (listMarker, bool) {
+	indent := indentation(line)
+	if indent > 3 || isThematicBreak(line) {
+		return listMarker{}, false
+	}
+
+	var lm listMarker
+	i := indent
+	switch {
+	case i == len(line):
+		return listMarker{}, false
+	case line[i] == '-' || line[i] == '*' || line[i] == '+':
+		lm.delimiter = line[i]
+		i++
+	default:
+		digits := i
+		for i < len(line) && i-digits < 9 && line[i] >= '0' && line[i] <= '9' {
+			i++
+		}
+		if i == digits || i == len(line) || line[i] != '.' && line[i] != ')' {
+			return listMarker{}, false
+		}
+		lm.ordered = true
+		lm.start, _ = strconv.Atoi(line[digits:i])
+		lm.delimiter = line[i]
+		i++
+	}
+	markerEnd := i
+	for i < len(line) && line[i] == ' ' {
+		i++
+	}
+	if i == markerEnd && i < len(line) {
+		return listMarker{}, false
+	}
+	marker, spaces, rest := line[indent:markerEnd], line[markerEnd:i], line[i:]
+	lm.rest = rest
+
+	// Content indented by 5 spaces or more is an indented code block, whose
+	// indentation counts from the first space after the marker.
+	if len(spaces) > 4 {
+		lm.rest = spaces[1:] + rest
+		spaces = " "
+	}
+	if spaces == "" {
+		spaces = " "
+	}
+	lm.contentIndent = indent + len(marker) + len(spaces)
+	return lm, true
+}
+
+func isListItem(line string) bool {
+	_, ok := parseListMarker(line)
+	return ok
+}
+
+// isThematicBreak reports whether line is a thematic break, only running the
+// regular expression on lines made of the characters of one.
+func isThematicBreak(line string) bool {
+	return strings.Trim(line, " \t*-_") == "" && thematicBreakRe.MatchString(line)
+}
+
+func (lm listMarker) sameList(other listMarker) bool {
+	return lm.ordered == other.ordered && lm.delimiter == other.delimiter
+}
+
+func (p *parser) list(lines []string, i int, out *strings.Builder) int {
+	first, _ := parseListMarker(lines[i])
+
+	var items [][]string
+	loose := false
+	marker := first
+	item := []string{marker.rest}
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			item = append(item, "")
+			continue
+		}
+
+		previousBlank := isBlank(item[len(item)-1])
+		if indentation(line) >= marker.contentIndent {
+			if previousBlank && len(trimBlankLines(item)) > 0 {
+				loose = true
+			}
+			item = append(item, line[marker.contentIndent:])
+			continue
+		}
+
+		if next, ok := parseListMarker(line); ok && next.sameList(first) {
+			if previousBlank {
+				loose = true
+			}
+			items = append(items, item)
+			marker = next
+			item = []string{marker.rest}
+			continue
+		}
+
+		if previousBlank || interruptsParagraph(line) {
+			break
+		}
+		// Lazy continuation of the last paragraph of the item
+		item = append(item, strings.TrimLeft(line, " "))
+	}
+	items = append(items, item)
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	out.WriteString("<" + tag)
+	if first.ordered && first.start != 1 {
+		out.WriteString(` start="` + strconv.Itoa(first.start) + `"`)
+	}
+	out.WriteString(">\n")
+	p.depth++
+	for _, item := range items {
+		out.WriteString("<li>")
+		var content strings.Builder
+		p.blocks(trimBlankLines(item), &content, !loose)
+		out.WriteString(strings.TrimSuffix(content.String(), "\n"))
+		out.WriteString("</li>\n")
+	}
+	p.depth--
+	out.WriteString("</" + tag + ">\n")
+
+	// Trailing blank lines end the list, they are not part of the last item.
+	for i > 0 && isBlank(lines[i-1]) {
+		i--
+	}
+	return i
+}
+
+func (p *parser) table(lines []string, i int, out *strings.Builder) int {
+	header := splitTableRow(lines[i])
+	var aligns []string
+	for _, cell := range splitTableRow(lines[i+1]) {
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns = append(aligns, "center")
+		case left:
+			aligns = append(aligns, "left")
+		case right:
+			aligns = append(aligns, "right")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	writeRow := func(cells []string, tag string) {
+		out.WriteString("<tr>\n")
+		for c, align := range aligns {
+			out.WriteString("<" + tag)
+			if align != "" {
+				out.WriteString(` align="` + align + `"`)
+			}
+			out.WriteString(">")
+			if c < len(cells) {
+				content := renderInline(cells[c], p.refs)
+				p.countWords(plainText(content))
+				out.WriteString(content)
+			}
+			out.WriteString("</" + tag + ">\n")
+		}
+		out.WriteString("</tr>\n")
+	}
+
+	out.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	out.WriteString("</thead>\n")
+
+	i += 2
+	if i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|") {
+		out.WriteString("<tbody>\n")
+		for ; i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+			writeRow(splitTableRow(lines[i]), "td")
+		}
+		out.WriteString("</tbody>\n")
+	}
+	out.WriteString("</table>\n")
+	return i
+}
+
+// splitTableRow splits a table row into its trimmed cells. Escaped pipes and
+// pipes in code spans don't separate cells.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '`':
+			inCode = !inCode
+			cell.WriteByte('`')
+		case line[i] == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+func (p *parser) paragraph(lines []string, i int, out *strings.Builder, tight bool) int {
+	var text []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(text) > 0 {
+			if m := setextRe.FindStringSubmatch(line); m != nil {
+				level := 2
+				if m[1][0] == '=' {
+					level = 1
+				}
+				p.heading(level, strings.Join(text, "\n"), out)
+				return i + 1
+			}
+			if interruptsParagraph(line) {
+				break
+			}
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+
+	// Link reference definitions at the start of the paragraph aren't part
+	// of it, and a paragraph of only definitions renders nothing.
+	source := strings.Join(text, "\n")
+	for {
+		label, ref, end, ok := linkReferenceDefinition(source)
+		if !ok {
+			break
+		}
+		p.refs.add(label, ref)
+		source = source[end:]
+	}
+	if strings.TrimSpace(source) == "" {
+		return i
+	}
+
+	content := renderInline(strings.TrimRight(source, " "), p.refs)
+	p.countWords(plainText(content))
+	if tight {
+		out.WriteString(content + "\n")
+	} else {
+		out.WriteString("<p>" + content + "</p>\n")
+	}
+	return i
+}
+
+func (p *parser) countWords(text string) {
+	p.doc.WordCount += len(strings.Fields(text))
+}
+
+// interruptsParagraph reports whether line starts a block even right after
+// the line of a paragraph.
+func interruptsParagraph(line string) bool {
+	if fenceRe.MatchString(line) || atxHeadingRe.MatchString(line) ||
+		isThematicBreak(line) || blockquoteRe.MatchString(line) {
+		return true
+	}
+	// Only lists starting at 1 and with an item that is not empty interrupt a
+	// paragraph, so that numbers starting a line don't make lists.
+	lm, ok := parseListMarker(line)
+	return ok && !isBlank(lm.rest) && (!lm.ordered || lm.start == 1)
+}
+
+func skipUntilBlank(lines []string, i int) int {
+	for i < len(lines) && !isBlank(lines[i]) {
+		i++
+	}
+	return i
+}
+
+func skipComment(lines []string, i int) int {
+	for ; i < len(lines); i++ {
+		if strings.Contains(lines[i], "-->") {
+			return i + 1
+		}
+	}
+	return i
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// trimIndent removes up to n spaces of indentation from line.
+func trimIndent(line string, n int) string {
+	if indent := indentation(line); indent < n {
+		n = indent
+	}
+	return line[n:]
+}
+
+func trimBlankLines(lines []string) []string {
+	for len(lines) > 0 && isBlank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
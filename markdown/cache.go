@@ -0,0 +1,58 @@
+package markdown
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// Cache keeps rendered documents in Redis, so that content is only rendered
+// once per revision. It is only an optimization: when Redis fails, content is
+// rendered anew.
+type Cache struct {
+	Sugar *zap.SugaredLogger
+	// Client is the Redis client. Without one, nothing is cached.
+	Client *redis.Client
+	// TTL is how long a rendering is kept after it was last rendered.
+	TTL time.Duration
+}
+
+// Render returns the rendering of source cached under key, rendering it on a
+// cache miss. The key must change whenever source does.
+func (c *Cache) Render(ctx context.Context, key string, source string) *Document {
+	if c == nil || c.Client == nil {
+		return Render(source)
+	}
+	key = "markdown:v" + strconv.Itoa(Version) + ":" + key
+
+	cached, err := c.Client.Get(ctx, key).Bytes()
+	if err == nil {
+		var doc Document
+		if err := json.Unmarshal(cached, &doc); err == nil {
+			return &doc
+		}
+	} else if err != redis.Nil {
+		c.logError("get", key, err)
+	}
+
+	doc := Render(source)
+	b, err := json.Marshal(doc)
+	if err != nil {
+		c.logError("encode", key, err)
+		return doc
+	}
+	if err := c.Client.Set(ctx, key, b, c.TTL).Err(); err != nil {
+		c.logError("set", key, err)
+	}
+	return doc
+}
+
+func (c *Cache) logError(op string, key string, err error) {
+	if c.Sugar != nil {
+		c.Sugar.Warnw("markdown: rendering cache failed", "op", op, "key", key, "error", err)
+	}
+}
@@ -0,0 +1,53 @@
+package markdown
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"hxann.com/blog/redistest"
+)
+
+func TestCache(t *testing.T) {
+	server, client := redistest.NewClient(t)
+	cache := &Cache{Sugar: zap.NewNop().Sugar(), Client: client, TTL: time.Hour}
+	ctx := context.Background()
+	key := "markdown:v" + strconv.Itoa(Version) + ":posts:hello:1"
+
+	doc := cache.Render(ctx, "posts:hello:1", "# Hello")
+	if doc.HTML != Render("# Hello").HTML {
+		t.Fatalf("got %q", doc.HTML)
+	}
+	if _, ok := server.Get(key); !ok {
+		t.Fatal("the rendering wasn't cached")
+	}
+	if ttl := server.TTL(key); ttl <= 0 || ttl > time.Hour {
+		t.Errorf("got a TTL of %v, want at most an hour", ttl)
+	}
+
+	// The cached rendering is returned, even though the source changed.
+	doc = cache.Render(ctx, "posts:hello:1", "# Changed")
+	if doc.HTML != Render("# Hello").HTML {
+		t.Errorf("got %q, want the cached rendering", doc.HTML)
+	}
+}
+
+func TestCacheRendersWhenRedisFails(t *testing.T) {
+	server, client := redistest.NewClient(t)
+	cache := &Cache{Sugar: zap.NewNop().Sugar(), Client: client, TTL: time.Hour}
+	server.SetFailing(true)
+
+	doc := cache.Render(context.Background(), "posts:hello:1", "# Hello")
+	if doc == nil || doc.HTML != Render("# Hello").HTML {
+		t.Errorf("got %+v, want the rendering", doc)
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var cache *Cache
+	if doc := cache.Render(context.Background(), "posts:hello:1", "# Hello"); doc.HTML != Render("# Hello").HTML {
+		t.Errorf("got %q", doc.HTML)
+	}
+}
@@ -0,0 +1,512 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	entityRe   = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[a-zA-Z][a-zA-Z0-9]{1,31});`)
+	autolinkRe = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailRe    = regexp.MustCompile(`^<([a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+	htmlTagRe  = regexp.MustCompile(`^</?([a-zA-Z][a-zA-Z0-9]*)\s*/?>`)
+	tagRe      = regexp.MustCompile(`<[^>]*>`)
+)
+
+// allowedTags are the HTML tags kept in the output. They are only kept
+// without attributes, any other HTML is escaped.
+var allowedTags = map[string]bool{
+	"b": true, "i": true, "em": true, "strong": true, "code": true, "kbd": true,
+	"sub": true, "sup": true, "mark": true, "del": true, "ins": true, "s": true,
+	"small": true, "u": true, "br": true,
+}
+
+// safeSchemes are the URL schemes allowed in links. Images only allow http
+// and https. URLs without a scheme are relative, thus safe.
+var safeSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+
+const escapable = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+// renderInline renders the inlines of a paragraph or heading, whose reference
+// links refer to refs.
+func renderInline(text string, refs linkReferences) string {
+	var out strings.Builder
+	writeInline(text, &out, refs)
+	return out.String()
+}
+
+func writeInline(s string, out *strings.Builder, refs linkReferences) {
+	// unclosed remembers, by delimiter and length, the earliest index from
+	// which no closer was found, so that unmatched delimiters don't make
+	// rendering quadratic.
+	unclosed := map[string]int{}
+	var brackets map[int]int
+	if strings.IndexByte(s, '[') >= 0 {
+		brackets = matchBrackets(s)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0 {
+				out.WriteString(html.EscapeString(s[i+1 : i+2]))
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\n' {
+				out.WriteString("<br />\n")
+				i += 2
+				continue
+			}
+
+		case '`':
+			if n, ok := codeSpan(s, i, out); ok {
+				i = n
+				continue
+			}
+			// An unmatched run of backticks is literal.
+			run := runLength(s, i)
+			out.WriteString(s[i : i+run])
+			i += run
+			continue
+
+		case '*', '_', '~':
+			i = emphasis(s, i, out, unclosed, refs)
+			continue
+
+		case '!':
+			if i+1 < len(s) && s[i+1] == '[' {
+				if n, ok := link(s, i+1, true, out, brackets, refs); ok {
+					i = n
+					continue
+				}
+			}
+
+		case '[':
+			if n, ok := link(s, i, false, out, brackets, refs); ok {
+				i = n
+				continue
+			}
+
+		case '<':
+			if n, ok := angleBracket(s, i, out); ok {
+				i = n
+				continue
+			}
+
+		case '&':
+			if entity := entityRe.FindString(s[i:]); entity != "" {
+				out.WriteString(entity)
+				i += len(entity)
+				continue
+			}
+
+		case ' ':
+			// Two trailing spaces make a hard line break.
+			run := runLength(s, i)
+			if i+run < len(s) && s[i+run] == '\n' {
+				if run >= 2 {
+					out.WriteString("<br />")
+				}
+				out.WriteByte('\n')
+				i += run + 1
+				continue
+			}
+		}
+
+		out.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+}
+
+// codeSpan writes the code span starting at i, and returns the index following
+// it.
+func codeSpan(s string, i int, out *strings.Builder) (int, bool) {
+	run := runLength(s, i)
+	for j := i + run; j < len(s); {
+		k := strings.IndexByte(s[j:], '`')
+		if k < 0 {
+			return 0, false
+		}
+		j += k
+		closing := runLength(s, j)
+		if closing != run {
+			j += closing
+			continue
+		}
+
+		code := strings.ReplaceAll(s[i+run:j], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		out.WriteString("<code>" + html.EscapeString(code) + "</code>")
+		return j + run, true
+	}
+	return 0, false
+}
+
+// emphasis writes the emphasis, strong emphasis or strikethrough opened by the
+// delimiter run at i, or the run itself if it opens nothing, and returns the
+// index following what it wrote.
+func emphasis(s string, i int, out *strings.Builder, unclosed map[string]int, refs linkReferences) int {
+	c := s[i]
+	run := runLength(s, i)
+
+	wants := []int{1}
+	tags := []string{"em"}
+	switch {
+	case c == '~':
+		wants, tags = []int{2}, []string{"del"}
+	case run >= 2:
+		wants, tags = []int{2, 1}, []string{"strong", "em"}
+	}
+
+	if canOpen(s, i, run, c) {
+		for k, want := range wants {
+			if run < want {
+				continue
+			}
+			end, ok := findCloser(s, i+want, c, want, unclosed)
+			if !ok {
+				continue
+			}
+			// Extra delimiters of the opening run are part of the content,
+			// e.g. ***a*** is <em><strong>a</strong></em>.
+			out.WriteString("<" + tags[k] + ">")
+			writeInline(s[i+want:end], out, refs)
+			out.WriteString("</" + tags[k] + ">")
+			return end + want
+		}
+	}
+
+	out.WriteString(s[i : i+run])
+	return i + run
+}
+
+// canOpen reports whether the delimiter run at i can open an emphasis: it must
+// be followed by a non-space and, for underscores, not be within a word.
+func canOpen(s string, i int, run int, c byte) bool {
+	next, _ := utf8.DecodeRuneInString(s[i+run:])
+	if i+run >= len(s) || unicode.IsSpace(next) {
+		return false
+	}
+	if c == '_' && i > 0 {
+		previous, _ := utf8.DecodeLastRuneInString(s[:i])
+		return !isWordRune(previous)
+	}
+	return true
+}
+
+// findCloser finds the run of at least want delimiters c that closes an
+// emphasis opened right before start, skipping code spans, escapes and nested
+// emphases. It returns the index of the last want delimiters of the run.
+func findCloser(s string, start int, c byte, want int, unclosed map[string]int) (int, bool) {
+	delimiter := strings.Repeat(string(c), want)
+	if from, ok := unclosed[delimiter]; ok && start >= from {
+		return 0, false
+	}
+
+	for j := start; j < len(s); {
+		switch s[j] {
+		case '\\':
+			j += 2
+			continue
+		case '`':
+			var discard strings.Builder
+			if n, ok := codeSpan(s, j, &discard); ok {
+				j = n
+				continue
+			}
+			j += runLength(s, j)
+			continue
+		case c:
+			run := runLength(s, j)
+			previous, _ := utf8.DecodeLastRuneInString(s[:j])
+			closes := j > start && run >= want && !unicode.IsSpace(previous)
+			if closes && c == '_' && j+run < len(s) {
+				next, _ := utf8.DecodeRuneInString(s[j+run:])
+				closes = !isWordRune(next)
+			}
+			if closes {
+				return j + run - want, true
+			}
+			// Skip nested emphases, e.g. the strong one of *a **b** c*.
+			if canOpen(s, j, run, c) {
+				nested := want
+				if c != '~' && run < 2 {
+					nested = 1
+				} else if c != '~' {
+					nested = 2
+				}
+				end, ok := findCloser(s, j+nested, c, nested, unclosed)
+				if ok {
+					j = end + nested
+					continue
+				}
+				// Whatever would close this emphasis would have closed the
+				// nested one.
+				if nested == want {
+					unclosed[delimiter] = start
+					return 0, false
+				}
+			}
+			j += run
+			continue
+		}
+		j++
+	}
+
+	unclosed[delimiter] = start
+	return 0, false
+}
+
+// link writes the link, or image, whose text starts with the bracket at i, and
+// returns the index following it. Besides inline links, like [text](/url),
+// there are reference links to the definitions of refs: full ones, like
+// [text][label], collapsed ones, like [label][], and shortcut ones, like
+// [label].
+func link(s string, i int, image bool, out *strings.Builder, brackets map[int]int, refs linkReferences) (int, bool) {
+	textEnd, ok := brackets[i]
+	if !ok {
+		return 0, false
+	}
+	text := s[i+1 : textEnd]
+
+	destination, title, end, ok := "", "", 0, false
+	if textEnd+1 < len(s) && s[textEnd+1] == '(' {
+		destination, title, end, ok = linkDestination(s, textEnd+2)
+	}
+	if !ok {
+		label, labelEnd := text, textEnd+1
+		if textEnd+1 < len(s) && s[textEnd+1] == '[' {
+			if textEnd+2 < len(s) && s[textEnd+2] == ']' {
+				labelEnd = textEnd + 3
+			} else if full, n, isLabel := linkLabel(s, textEnd+1); isLabel {
+				label, labelEnd = full, n
+			}
+		}
+		ref, found := refs.lookup(label)
+		if !found {
+			return 0, false
+		}
+		destination, title, end = ref.destination, ref.title, labelEnd
+	}
+
+	url, safe := safeURL(destination, image)
+	if image {
+		var alt strings.Builder
+		writeInline(text, &alt, refs)
+		if !safe {
+			// Unsafe images are left out, with their alternative text.
+			out.WriteString(alt.String())
+			return end, true
+		}
+		out.WriteString(`<img src="` + html.EscapeString(url) + `" alt="` + html.EscapeString(plainText(alt.String())) + `"`)
+		if title != "" {
+			out.WriteString(` title="` + html.EscapeString(title) + `"`)
+		}
+		out.WriteString(" />")
+		return end, true
+	}
+
+	if !safe {
+		writeInline(text, out, refs)
+		return end, true
+	}
+	out.WriteString(`<a href="` + html.EscapeString(url) + `"`)
+	if title != "" {
+		out.WriteString(` title="` + html.EscapeString(title) + `"`)
+	}
+	out.WriteString(">")
+	writeInline(text, out, refs)
+	out.WriteString("</a>")
+	return end, true
+}
+
+// matchBrackets returns the index of the closing bracket of every opening
+// bracket of s that is closed, skipping code spans and escapes.
+func matchBrackets(s string) map[int]int {
+	brackets := map[int]int{}
+	var open []int
+	for j := 0; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			var discard strings.Builder
+			if n, ok := codeSpan(s, j, &discard); ok {
+				j = n - 1
+			} else {
+				j += runLength(s, j) - 1
+			}
+		case '[':
+			open = append(open, j)
+		case ']':
+			if len(open) > 0 {
+				brackets[open[len(open)-1]] = j
+				open = open[:len(open)-1]
+			}
+		}
+	}
+	return brackets
+}
+
+// linkDestination parses the destination and optional title of a link, from
+// right after its opening parenthesis, and returns the index following the
+// closing parenthesis.
+func linkDestination(s string, i int) (string, string, int, bool) {
+	i = skipSpaces(s, i)
+
+	var destination string
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i+1:], ">\n")
+		if end < 0 || s[i+1+end] != '>' {
+			return "", "", 0, false
+		}
+		destination = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		start, depth := i, 0
+		for ; i < len(s) && s[i] > ' '; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			} else if s[i] == '(' {
+				depth++
+			} else if s[i] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		destination = s[start:i]
+	}
+
+	i = skipSpaces(s, i)
+	var title string
+	if i < len(s) && (s[i] == '"' || s[i] == '\'' || s[i] == '(') {
+		closing := s[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		end := indexUnescaped(s[i+1:], closing)
+		if end < 0 {
+			return "", "", 0, false
+		}
+		title = s[i+1 : i+1+end]
+		i = skipSpaces(s, i+end+2)
+	}
+
+	if i >= len(s) || s[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescape(destination), unescape(title), i + 1, true
+}
+
+// angleBracket writes the autolink or allowed HTML tag at i.
+func angleBracket(s string, i int, out *strings.Builder) (int, bool) {
+	if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil {
+		url, safe := safeURL(m[1], false)
+		if !safe {
+			return 0, false
+		}
+		out.WriteString(`<a href="` + html.EscapeString(url) + `">` + html.EscapeString(m[1]) + "</a>")
+		return i + len(m[0]), true
+	}
+	if m := emailRe.FindStringSubmatch(s[i:]); m != nil {
+		out.WriteString(`<a href="mailto:` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+		return i + len(m[0]), true
+	}
+	if m := htmlTagRe.FindStringSubmatch(s[i:]); m != nil {
+		name := strings.ToLower(m[1])
+		if !allowedTags[name] {
+			return 0, false
+		}
+		switch {
+		case name == "br":
+			out.WriteString("<br />")
+		case strings.HasPrefix(m[0], "</"):
+			out.WriteString("</" + name + ">")
+		default:
+			out.WriteString("<" + name + ">")
+		}
+		return i + len(m[0]), true
+	}
+	return 0, false
+}
+
+// safeURL returns the URL if its scheme is allowed.
+func safeURL(url string, image bool) (string, bool) {
+	url = strings.TrimSpace(url)
+	colon := strings.IndexByte(url, ':')
+	if colon < 0 || strings.ContainsAny(url[:colon], "/?#") {
+		return url, true
+	}
+
+	// Browsers ignore control characters and spaces in schemes.
+	scheme := strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, url[:colon]))
+	if image {
+		return url, scheme == "http" || scheme == "https"
+	}
+	return url, safeSchemes[scheme]
+}
+
+// plainText returns the text of rendered inlines, without tags.
+func plainText(rendered string) string {
+	return html.UnescapeString(tagRe.ReplaceAllString(rendered, ""))
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return html.UnescapeString(s)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escapable, s[i+1]) >= 0 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return html.UnescapeString(b.String())
+}
+
+// indexUnescaped returns the index of the first c of s not escaped with a
+// backslash, or -1.
+func indexUnescaped(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func runLength(s string, i int) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,128 @@
+// Package markdown renders the Markdown content of posts to HTML, along with a
+// table of contents and a word count.
+//
+// It supports the usual CommonMark blocks and inlines, including reference
+// links, GitHub tables and strikethrough. The output is safe to embed as is:
+// raw HTML is escaped, but a few formatting tags without attributes, links and
+// images are limited to safe URL schemes. MDX import and export statements and
+// JSX components, which can't be rendered on the server, are left out.
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Version is bumped whenever the output of Render changes, so that cached
+// renderings can be told apart.
+const Version = 3
+
+// wordsPerMinute is the reading speed used to compute reading times.
+const wordsPerMinute = 200
+
+// Document is rendered Markdown.
+type Document struct {
+	HTML string `json:"html"`
+	// TOC lists every heading in document order. Clients nest them by level.
+	TOC       []Heading `json:"toc"`
+	WordCount int       `json:"word_count"`
+}
+
+// Heading is an entry of the table of contents. Id is the id attribute of the
+// heading in the HTML, so it can be linked to as #id.
+type Heading struct {
+	Level int    `json:"level"`
+	Id    string `json:"id"`
+	Text  string `json:"text"`
+}
+
+// ReadingTime returns the number of minutes it takes to read the document,
+// rounded up.
+func (d *Document) ReadingTime() int {
+	return (d.WordCount + wordsPerMinute - 1) / wordsPerMinute
+}
+
+// Render renders the Markdown source to HTML.
+func Render(source string) *Document {
+	lines := splitLines(source)
+
+	// Links may come before the definitions they refer to, so a first pass
+	// collects the definitions.
+	refs := linkReferences{}
+	newParser(refs).blocks(lines, &strings.Builder{}, false)
+
+	p := newParser(refs)
+	var out strings.Builder
+	p.blocks(lines, &out, false)
+	p.doc.HTML = out.String()
+
+	return p.doc
+}
+
+func newParser(refs linkReferences) *parser {
+	return &parser{
+		doc:  &Document{TOC: []Heading{}},
+		ids:  map[string]int{},
+		refs: refs,
+	}
+}
+
+// headingId returns a unique id for a heading made of its text.
+func (p *parser) headingId(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			dash = true
+		}
+	}
+
+	id := b.String()
+	if id == "" {
+		id = "section"
+	}
+	if n := p.ids[id]; n > 0 {
+		p.ids[id]++
+		id += "-" + strconv.Itoa(n)
+	}
+	p.ids[id]++
+	return id
+}
+
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	lines := strings.Split(source, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+	return lines
+}
+
+// expandTabs replaces the tabs of the indentation of line with spaces, up to
+// the next multiple of 4 columns.
+func expandTabs(line string) string {
+	if !strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+		return line
+	}
+
+	var b strings.Builder
+	i := 0
+	for ; i < len(line) && (line[i] == ' ' || line[i] == '\t'); i++ {
+		if line[i] == '\t' {
+			b.WriteString(strings.Repeat(" ", 4-b.Len()%4))
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteString(line[i:])
+	return b.String()
+}
@@ -0,0 +1,298 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		html   string
+	}{
+		{
+			name:   "paragraphs",
+			source: "Hello,\nworld.\n\nBye.",
+			html:   "<p>Hello,\nworld.</p>\n<p>Bye.</p>\n",
+		},
+		{
+			name:   "headings",
+			source: "# Title #\n\nSetext\n------",
+			html:   "<h1 id=\"title\">Title</h1>\n<h2 id=\"setext\">Setext</h2>\n",
+		},
+		{
+			name:   "emphasis",
+			source: "*a* **b** ***c*** ~~d~~ snake_case_name",
+			html:   "<p><em>a</em> <strong>b</strong> <em><strong>c</strong></em> <del>d</del> snake_case_name</p>\n",
+		},
+		{
+			name:   "unclosed emphasis",
+			source: "**a *b",
+			html:   "<p>**a *b</p>\n",
+		},
+		{
+			name:   "code span",
+			source: "Use `a < b` or ``x ` y``.",
+			html:   "<p>Use <code>a &lt; b</code> or <code>x ` y</code>.</p>\n",
+		},
+		{
+			name:   "fenced code",
+			source: "```go\nfmt.Println(\"<hi>\")\n```",
+			html:   "<pre><code class=\"language-go\">fmt.Println(&#34;&lt;hi&gt;&#34;)\n</code></pre>\n",
+		},
+		{
+			name:   "indented code",
+			source: "    a\n    b",
+			html:   "<pre><code>a\nb\n</code></pre>\n",
+		},
+		{
+			name:   "blockquote",
+			source: "> quoted\nlazy",
+			html:   "<blockquote>\n<p>quoted\nlazy</p>\n</blockquote>\n",
+		},
+		{
+			name:   "tight list",
+			source: "- a\n- b",
+			html:   "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n",
+		},
+		{
+			name:   "loose ordered list",
+			source: "3. a\n\n4. b",
+			html:   "<ol start=\"3\">\n<li><p>a</p></li>\n<li><p>b</p></li>\n</ol>\n",
+		},
+		{
+			name:   "table",
+			source: "| a | b |\n|:--|--:|\n| 1 | `|` |",
+			html:   "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"right\">b</th>\n</tr>\n</thead>\n<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"right\"><code>|</code></td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name:   "thematic break",
+			source: "a\n\n***",
+			html:   "<p>a</p>\n<hr />\n",
+		},
+		{
+			name:   "hard line breaks",
+			source: "a  \nb\\\nc",
+			html:   "<p>a<br />\nb<br />\nc</p>\n",
+		},
+		{
+			name:   "inline link",
+			source: `[a *b*](/posts/x "Title") ![alt *text*](https://example.com/i.png)`,
+			html:   "<p><a href=\"/posts/x\" title=\"Title\">a <em>b</em></a> <img src=\"https://example.com/i.png\" alt=\"alt text\" /></p>\n",
+		},
+		{
+			name:   "autolinks",
+			source: "<https://example.com> <me@example.com>",
+			html:   "<p><a href=\"https://example.com\">https://example.com</a> <a href=\"mailto:me@example.com\">me@example.com</a></p>\n",
+		},
+		{
+			name:   "escapes and entities",
+			source: `\*not emphasis\* &copy; &notanentity`,
+			html:   "<p>*not emphasis* &copy; &amp;notanentity</p>\n",
+		},
+		{
+			name:   "MDX",
+			source: "import X from './x'\n\n<Chart data={1} />\n\ntext",
+			html:   "<p>text</p>\n",
+		},
+		{
+			name:   "comment",
+			source: "<!-- hidden\nstill hidden -->\ntext",
+			html:   "<p>text</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Render(test.source).HTML; got != test.html {
+				t.Errorf("Render(%q)\ngot  %q\nwant %q", test.source, got, test.html)
+			}
+		})
+	}
+}
+
+func TestRenderReferenceLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		html   string
+	}{
+		{
+			name:   "full",
+			source: "[text][Label]\n\n[label]: /url",
+			html:   "<p><a href=\"/url\">text</a></p>\n",
+		},
+		{
+			name:   "collapsed",
+			source: "[Label][]\n\n[label]: /url",
+			html:   "<p><a href=\"/url\">Label</a></p>\n",
+		},
+		{
+			name:   "shortcut",
+			source: "[label] and ![label]\n\n[label]: https://example.com/a.png",
+			html:   "<p><a href=\"https://example.com/a.png\">label</a> and <img src=\"https://example.com/a.png\" alt=\"label\" /></p>\n",
+		},
+		{
+			name:   "defined before use",
+			source: "[x]: <https://example.com/a b> 'Title'\n\n[x]",
+			html:   "<p><a href=\"https://example.com/a b\" title=\"Title\">x</a></p>\n",
+		},
+		{
+			name:   "title on the next line",
+			source: "[x]: /url\n  \"Title\"\n\n[x]",
+			html:   "<p><a href=\"/url\" title=\"Title\">x</a></p>\n",
+		},
+		{
+			name:   "label case and whitespace",
+			source: "[Foo\n  Bar]\n\n[foo bar]: /url",
+			html:   "<p><a href=\"/url\">Foo\nBar</a></p>\n",
+		},
+		{
+			name:   "first definition wins",
+			source: "[x]\n\n[x]: /first\n[x]: /second",
+			html:   "<p><a href=\"/first\">x</a></p>\n",
+		},
+		{
+			name:   "definitions then text",
+			source: "[a]: /a\n[b]: /b\nSee [a] and [b].",
+			html:   "<p>See <a href=\"/a\">a</a> and <a href=\"/b\">b</a>.</p>\n",
+		},
+		{
+			name:   "in a list",
+			source: "- [x]\n\n  [x]: /url",
+			html:   "<ul>\n<li><p><a href=\"/url\">x</a></p></li>\n</ul>\n",
+		},
+		{
+			name:   "undefined",
+			source: "[x] [y][x] [z][]",
+			html:   "<p>[x] [y][x] [z][]</p>\n",
+		},
+		{
+			name:   "inline link first",
+			source: "[x](/inline)\n\n[x]: /reference",
+			html:   "<p><a href=\"/inline\">x</a></p>\n",
+		},
+		{
+			name:   "not a definition",
+			source: "[x]: /url trailing text",
+			html:   "<p>[x]: /url trailing text</p>\n",
+		},
+		{
+			name:   "unsafe",
+			source: "[x]\n\n[x]: javascript:alert(1)",
+			html:   "<p>x</p>\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Render(test.source).HTML; got != test.html {
+				t.Errorf("Render(%q)\ngot  %q\nwant %q", test.source, got, test.html)
+			}
+		})
+	}
+}
+
+// The output is embedded as is, so none of these may get through.
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		source string
+		html   string
+	}{
+		{`<script>alert(1)</script>`, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{`<img src=x onerror=alert(1)>`, "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+		{`<b onclick="x">bold</b>`, "<p>&lt;b onclick=&#34;x&#34;&gt;bold</b></p>\n"},
+		{`<b>bold</b> <KBD>k</KBD>`, "<p><b>bold</b> <kbd>k</kbd></p>\n"},
+		{`[x](javascript:alert(1))`, "<p>x</p>\n"},
+		{`[x](JaVaScRiPt:alert(1))`, "<p>x</p>\n"},
+		{"[x](java\tscript:alert(1))", "<p>[x](java\tscript:alert(1))</p>\n"},
+		{`[x](<java script:alert(1)>)`, "<p>x</p>\n"},
+		{`[x](data:text/html,<script>)`, "<p>x</p>\n"},
+		{`![x](data:image/png;base64,AAAA)`, "<p>x</p>\n"},
+		{`![x](mailto:me@example.com)`, "<p>x</p>\n"},
+		{`<javascript:alert(1)>`, "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+		{`[x](/a "t\" onmouseover=\"alert(1)")`, "<p><a href=\"/a\" title=\"t&#34; onmouseover=&#34;alert(1)\">x</a></p>\n"},
+		{`[x](/a?b="c")`, "<p><a href=\"/a?b=&#34;c&#34;\">x</a></p>\n"},
+		{"```\"><script>\n</script>\n```", "<pre><code class=\"language-&#34;&gt;&lt;script&gt;\">&lt;/script&gt;\n</code></pre>\n"},
+		{"# <script>x</script>", "<h1 id=\"scriptxscript\">&lt;script&gt;x&lt;/script&gt;</h1>\n"},
+	}
+
+	for _, test := range tests {
+		html := Render(test.source).HTML
+		if html != test.html {
+			t.Errorf("Render(%q)\ngot  %q\nwant %q", test.source, html, test.html)
+		}
+		lower := strings.ToLower(html)
+		for _, unsafe := range []string{"<script", "<img src=x", `href="javascript:`, `src="data:`, `href="data:`} {
+			if strings.Contains(lower, unsafe) {
+				t.Errorf("Render(%q) = %q contains %s", test.source, html, unsafe)
+			}
+		}
+	}
+}
+
+func TestRenderTOCAndWordCount(t *testing.T) {
+	doc := Render("# Intro\n\nOne two three.\n\n## Intro\n\n### *Deep* `code`\n\n| a b | c |\n|---|---|\n| d | e |\n\n    not counted")
+
+	want := []Heading{
+		{Level: 1, Id: "intro", Text: "Intro"},
+		{Level: 2, Id: "intro-1", Text: "Intro"},
+		{Level: 3, Id: "deep-code", Text: "Deep code"},
+	}
+	if !reflect.DeepEqual(doc.TOC, want) {
+		t.Errorf("got TOC %+v, want %+v", doc.TOC, want)
+	}
+	if doc.WordCount != 12 {
+		t.Errorf("got %d words, want 12", doc.WordCount)
+	}
+	if got := doc.ReadingTime(); got != 1 {
+		t.Errorf("got a reading time of %d minutes, want 1", got)
+	}
+	if got := (&Document{WordCount: 401}).ReadingTime(); got != 3 {
+		t.Errorf("got a reading time of %d minutes for 401 words, want 3", got)
+	}
+}
+
+// Every level of nesting parses the lines of the levels below it again, so
+// deeply nested content must not make rendering quadratic.
+func TestRenderDeepNesting(t *testing.T) {
+	var indented strings.Builder
+	for i := 0; i < 250; i++ {
+		indented.WriteString(strings.Repeat("  ", i) + "- a\n")
+	}
+	tests := []struct {
+		name   string
+		source string
+		tag    string
+	}{
+		// Only dashes would be a thematic break
+		{"bullets", strings.Repeat("- ", 32000) + "a", "<ul>"},
+		{"numbers", strings.Repeat("1. ", 21000), "<ol>"},
+		{"blockquotes", strings.Repeat("> ", 32000), "<blockquote>"},
+		{"indented items", indented.String(), "<ul>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			doc := Render(test.source)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("rendering %d bytes took %v", len(test.source), elapsed)
+			}
+			if got := strings.Count(doc.HTML, test.tag); got != maxNesting {
+				t.Errorf("got %d nested %s, want %d", got, test.tag, maxNesting)
+			}
+		})
+	}
+}
+
+func TestRenderNestingLimit(t *testing.T) {
+	source := strings.Repeat("- ", maxNesting) + "- a"
+	want := strings.Repeat("<ul>\n<li>", maxNesting) + "- a</li>\n</ul>" + strings.Repeat("</li>\n</ul>", maxNesting-1) + "\n"
+	if got := Render(source).HTML; got != want {
+		t.Errorf("Render(%q)\ngot  %q\nwant %q", source, got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package markdown
+
+import "strings"
+
+// maxLabelLength is the longest link label, as in CommonMark.
+const maxLabelLength = 999
+
+// linkReference is the destination and title given to a label by a link
+// reference definition, like [label]: /url "title".
+type linkReference struct {
+	destination string
+	title       string
+}
+
+// linkReferences are the link reference definitions of a document, by
+// normalized label.
+type linkReferences map[string]linkReference
+
+// add defines label, unless it already is: the first definition wins.
+func (refs linkReferences) add(label string, ref linkReference) {
+	key := normalizeLabel(label)
+	if _, ok := refs[key]; !ok {
+		refs[key] = ref
+	}
+}
+
+func (refs linkReferences) lookup(label string) (linkReference, bool) {
+	ref, ok := refs[normalizeLabel(label)]
+	return ref, ok
+}
+
+// normalizeLabel makes labels that only differ in case and whitespace equal.
+func normalizeLabel(label string) string {
+	return strings.ToLower(strings.ToUpper(strings.Join(strings.Fields(label), " ")))
+}
+
+// linkReferenceDefinition parses the link reference definition at the start of
+// s, the text of a paragraph, and returns its label and the index following
+// it.
+func linkReferenceDefinition(s string) (string, linkReference, int, bool) {
+	label, i, ok := linkLabel(s, 0)
+	if !ok || i >= len(s) || s[i] != ':' {
+		return "", linkReference{}, 0, false
+	}
+
+	i = skipSpacesAndNewline(s, i+1)
+	var destination string
+	if i < len(s) && s[i] == '<' {
+		end := strings.IndexAny(s[i+1:], "<>\n")
+		if end < 0 || s[i+1+end] != '>' {
+			return "", linkReference{}, 0, false
+		}
+		destination = s[i+1 : i+1+end]
+		i += end + 2
+	} else {
+		start, depth := i, 0
+		for ; i < len(s) && s[i] > ' '; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+			} else if s[i] == '(' {
+				depth++
+			} else if s[i] == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+		}
+		if i == start || depth != 0 {
+			return "", linkReference{}, 0, false
+		}
+		destination = s[start:i]
+	}
+	ref := linkReference{destination: unescape(destination)}
+
+	// The title, which may start on the next line, must be followed by the end
+	// of its line. Without one, the destination must be.
+	afterDestination, destinationEndsLine := nextLine(s, i)
+	titleStart := skipSpacesAndNewline(s, i)
+	if titleStart > i && titleStart < len(s) && (s[titleStart] == '"' || s[titleStart] == '\'' || s[titleStart] == '(') {
+		closing := s[titleStart]
+		if closing == '(' {
+			closing = ')'
+		}
+		if end := indexUnescaped(s[titleStart+1:], closing); end >= 0 {
+			if next, ok := nextLine(s, titleStart+end+2); ok {
+				ref.title = unescape(s[titleStart+1 : titleStart+1+end])
+				return label, ref, next, true
+			}
+		}
+	}
+	if !destinationEndsLine {
+		return "", linkReference{}, 0, false
+	}
+	return label, ref, afterDestination, true
+}
+
+// linkLabel parses the link label starting with the bracket at i, and returns
+// it and the index following its closing bracket. Labels can't contain
+// unescaped brackets.
+func linkLabel(s string, i int) (string, int, bool) {
+	if i >= len(s) || s[i] != '[' {
+		return "", 0, false
+	}
+	for j := i + 1; j < len(s) && j-i-1 <= maxLabelLength; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			return "", 0, false
+		case ']':
+			label := s[i+1 : j]
+			if strings.TrimSpace(label) == "" {
+				return "", 0, false
+			}
+			return label, j + 1, true
+		}
+	}
+	return "", 0, false
+}
+
+// nextLine returns the index of the line following i, if only spaces follow i
+// on its line.
+func nextLine(s string, i int) (int, bool) {
+	for ; i < len(s) && (s[i] == ' ' || s[i] == '\t'); i++ {
+	}
+	switch {
+	case i == len(s):
+		return i, true
+	case s[i] == '\n':
+		return i + 1, true
+	}
+	return 0, false
+}
+
+// skipSpacesAndNewline skips spaces, including at most one line break.
+func skipSpacesAndNewline(s string, i int) int {
+	newline := false
+	for ; i < len(s); i++ {
+		switch {
+		case s[i] == ' ' || s[i] == '\t':
+		case s[i] == '\n' && !newline:
+			newline = true
+		default:
+			return i
+		}
+	}
+	return i
+}
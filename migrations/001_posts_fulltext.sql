@@ -0,0 +1,2 @@
+ALTER TABLE `posts`
+	ADD FULLTEXT INDEX `posts_fulltext` (`title`, `excerpt`, `content`);
@@ -0,0 +1,16 @@
+CREATE TABLE `tags` (
+	`slug` varchar(255) NOT NULL,
+	`name` varchar(255) NOT NULL,
+	PRIMARY KEY (`slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_tags` (
+	`post_slug` varchar(255) NOT NULL,
+	`tag_slug` varchar(255) NOT NULL,
+	PRIMARY KEY (`post_slug`, `tag_slug`),
+	KEY `posts_tags_tag_slug` (`tag_slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
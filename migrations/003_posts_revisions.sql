@@ -0,0 +1,13 @@
+CREATE TABLE `posts_revisions` (
+	`id` bigint unsigned NOT NULL AUTO_INCREMENT,
+	`post_slug` varchar(255) NOT NULL,
+	`title` varchar(1000) NOT NULL,
+	`excerpt` varchar(1000) NOT NULL,
+	`content` text NOT NULL,
+	`editor_user_id` varchar(500) NOT NULL,
+	`created_at` datetime NOT NULL,
+	PRIMARY KEY (`id`),
+	KEY `posts_revisions_post_slug` (`post_slug`, `id`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
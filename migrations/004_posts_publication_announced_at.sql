@@ -0,0 +1,9 @@
+ALTER TABLE `posts_publication`
+	ADD COLUMN `announced_at` datetime,
+	ADD KEY `posts_publication_announced_at` (`announced_at`, `published_at`);
+
+-- Every post published so far was live right away, so none of them must be
+-- announced. Marking them all avoids comparing with the clock of MySQL, as
+-- datetimes are in the local time of the app.
+UPDATE `posts_publication`
+SET `announced_at` = `published_at`;
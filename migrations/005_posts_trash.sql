@@ -0,0 +1,9 @@
+CREATE TABLE `posts_trash` (
+	`post_slug` varchar(255) NOT NULL,
+	`trashed_at` datetime NOT NULL,
+	`trashed_by` varchar(500) NOT NULL,
+	PRIMARY KEY (`post_slug`),
+	KEY `posts_trash_trashed_at` (`trashed_at`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
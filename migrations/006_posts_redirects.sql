@@ -0,0 +1,9 @@
+CREATE TABLE `posts_redirects` (
+	`old_slug` varchar(255) NOT NULL,
+	`post_slug` varchar(255) NOT NULL,
+	`created_at` datetime NOT NULL,
+	PRIMARY KEY (`old_slug`),
+	KEY `posts_redirects_post_slug` (`post_slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,22 @@
+CREATE TABLE `pages` (
+	`slug` varchar(255) NOT NULL,
+	`title` varchar(1000) NOT NULL,
+	`excerpt` varchar(1000) NOT NULL,
+	`content` text NOT NULL DEFAULT (_utf8mb4 ''),
+	`menu_order` int NOT NULL DEFAULT 0,
+	`published_at` datetime,
+	`modified_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+	PRIMARY KEY (`slug`),
+	KEY `pages_menu_order` (`menu_order`, `slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `pages_authors` (
+	`page_slug` varchar(255) NOT NULL,
+	`author_user_id` varchar(500) NOT NULL,
+	`is_original` tinyint(1) NOT NULL,
+	PRIMARY KEY (`page_slug`, `author_user_id`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,23 @@
+CREATE TABLE `media` (
+	`id` bigint unsigned NOT NULL AUTO_INCREMENT,
+	`storage_key` varchar(500) NOT NULL,
+	`url` varchar(1000) NOT NULL,
+	`filename` varchar(255) NOT NULL,
+	`content_type` varchar(255) NOT NULL,
+	`size` bigint NOT NULL,
+	`author_user_id` varchar(500) NOT NULL,
+	`created_at` datetime NOT NULL,
+	PRIMARY KEY (`id`),
+	KEY `media_author_user_id` (`author_user_id`, `created_at`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_media` (
+	`post_slug` varchar(255) NOT NULL,
+	`media_id` bigint unsigned NOT NULL,
+	PRIMARY KEY (`post_slug`, `media_id`),
+	KEY `posts_media_media_id` (`media_id`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,5 @@
+ALTER TABLE `posts_cover_url`
+	ADD COLUMN `derivatives` json;
+
+ALTER TABLE `media`
+	ADD COLUMN `derivatives` json;
@@ -0,0 +1,5 @@
+ALTER TABLE `posts`
+	ADD COLUMN `version` int NOT NULL DEFAULT 1;
+
+ALTER TABLE `authors`
+	ADD COLUMN `version` int NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+ALTER TABLE `posts_cover_url`
+	ADD COLUMN `cover_key` varchar(500) AFTER `cover_url`;
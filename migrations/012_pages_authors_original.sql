@@ -0,0 +1,2 @@
+ALTER TABLE `pages_authors`
+	ADD UNIQUE KEY `pages_authors_UN` (`page_slug`, `is_original`);
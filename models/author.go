@@ -9,6 +9,9 @@ type Author struct {
 	FullName string `json:"full_name"`
 	Email    string `json:"email"`
 	Bio      string `json:"bio"`
+	// Version is incremented by every update. Authors of posts are listed
+	// without it.
+	Version int `json:"version,omitempty"`
 }
 
 type AuthorModel struct {
@@ -51,12 +54,12 @@ func (m AuthorModel) Get(userId string) (*Author, error) {
 	var author Author = Author{UserId: userId}
 
 	err := m.DB.QueryRow(`
-		SELECT full_name, email, bio
+		SELECT full_name, email, bio, version
 		FROM authors
-		WHERE user_id = ?`, userId).Scan(&author.FullName, &author.Email, &author.Bio)
+		WHERE user_id = ?`, userId).Scan(&author.FullName, &author.Email, &author.Bio, &author.Version)
 
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "author %s not found", userId)
 	}
 
 	return &author, nil
@@ -68,20 +71,39 @@ func (m AuthorModel) Add(author *Author) error {
 		(user_id, full_name, email, bio)
 		VALUES (?, ?, ?, ?)`, author.UserId, author.FullName, author.Email, author.Bio)
 	if err != nil {
-		return err
+		return duplicate(err, "author %s already exists", author.UserId)
 	}
+	author.Version = 1
 
 	return nil
 }
 
-func (m AuthorModel) Update(newAuthor *Author) error {
-	_, err := m.DB.Exec(`
+// Update updates the author. Unless version is 0, the author must still be at
+// that version, or ErrStaleVersion is returned.
+func (m AuthorModel) Update(newAuthor *Author, version int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	version, err = lockVersion(tx, version, `SELECT version FROM authors WHERE user_id = ?`, newAuthor.UserId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
 		UPDATE authors
-		SET full_name=?, email=?, bio=?
+		SET full_name=?, email=?, bio=?, version=version+1
 		WHERE user_id=?`, newAuthor.FullName, newAuthor.Email, newAuthor.Bio, newAuthor.UserId)
 	if err != nil {
 		return err
 	}
+	newAuthor.Version = version + 1
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
 
 	return nil
 }
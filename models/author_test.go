@@ -0,0 +1,23 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAuthorAddDuplicate(t *testing.T) {
+	authors := AuthorModel{DB: testDB(t)}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := authors.Add(author); err != nil {
+		t.Fatal(err)
+	}
+	if author.Version != 1 {
+		t.Errorf("got version %d, want 1", author.Version)
+	}
+
+	err := authors.Add(&Author{UserId: "author", FullName: "Other", Email: "other@example.com"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("got %v, want a duplicate error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var createTableRe = regexp.MustCompile("^CREATE TABLE `([a-z_]+)`")
+
+// testDB connects to the MySQL database of $TEST_DSN and recreates the tables
+// of schema.sql in it, wiping their rows. Tests that need a database are
+// skipped if $TEST_DSN is unset.
+func testDB(tb testing.TB) *sql.DB {
+	tb.Helper()
+
+	dsn := os.Getenv("TEST_DSN")
+	if dsn == "" {
+		tb.Skip("$TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	schema, err := os.ReadFile("../schema.sql")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	for _, stmt := range strings.Split(string(schema), ";\n") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		if match := createTableRe.FindStringSubmatch(stmt); match != nil {
+			if _, err := db.Exec("DROP TABLE IF EXISTS `" + match[1] + "`"); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		if _, err := db.Exec(stmt); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	return db
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Kinds of domain errors. Errors returned by models are classified with
+// errors.Is, such as errors.Is(err, ErrNotFound). Any other error is internal.
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrDuplicate is a conflict with an existing resource of the same key.
+	ErrDuplicate = errors.New("duplicate")
+	ErrConflict  = errors.New("conflict")
+	ErrForbidden = errors.New("forbidden")
+	ErrInvalid   = errors.New("invalid")
+)
+
+// Error is a domain error of Kind, one of the kinds above. Message is meant
+// for clients, and Err is the underlying error, if any.
+type Error struct {
+	Kind    error
+	Message string
+	Err     error
+}
+
+func (err *Error) Error() string {
+	return err.Message
+}
+
+func (err *Error) Is(target error) bool {
+	return target == err.Kind
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+func NotFound(format string, a ...interface{}) error {
+	return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, a...)}
+}
+
+func Duplicate(format string, a ...interface{}) error {
+	return &Error{Kind: ErrDuplicate, Message: fmt.Sprintf(format, a...)}
+}
+
+func Conflict(format string, a ...interface{}) error {
+	return &Error{Kind: ErrConflict, Message: fmt.Sprintf(format, a...)}
+}
+
+func Forbidden(format string, a ...interface{}) error {
+	return &Error{Kind: ErrForbidden, Message: fmt.Sprintf(format, a...)}
+}
+
+func Invalid(format string, a ...interface{}) error {
+	return &Error{Kind: ErrInvalid, Message: fmt.Sprintf(format, a...)}
+}
+
+// notFound turns sql.ErrNoRows into a NotFound error described by format,
+// and returns other errors as they are.
+func notFound(err error, format string, a ...interface{}) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Kind: ErrNotFound, Message: fmt.Sprintf(format, a...), Err: err}
+	}
+	return err
+}
+
+// duplicate turns duplicate key errors of MySQL into a Duplicate error
+// described by format, and returns other errors as they are.
+func duplicate(err error, format string, a ...interface{}) error {
+	var driverErr *mysql.MySQLError
+	if errors.As(err, &driverErr) && driverErr.Number == 1062 {
+		return &Error{Kind: ErrDuplicate, Message: fmt.Sprintf(format, a...), Err: err}
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// ImageDerivatives are the smaller versions of an uploaded image, generated so
+// that clients don't have to download the original.
+type ImageDerivatives struct {
+	// Variants are the image resized to several widths, narrowest first.
+	Variants []ImageVariant `json:"variants"`
+	// Thumbnails are the image resized to the thumbnail width in several
+	// formats, keyed by content type.
+	Thumbnails map[string]string `json:"thumbnails"`
+	// Placeholder is a data URI of a tiny version of the image, meant to be
+	// shown blurred while the image loads.
+	Placeholder string `json:"placeholder"`
+}
+
+// urls returns the URLs of the variants and thumbnails.
+func (d *ImageDerivatives) urls() []string {
+	if d == nil {
+		return nil
+	}
+	var urls []string
+	for _, variant := range d.Variants {
+		urls = append(urls, variant.Url)
+	}
+	for _, url := range d.Thumbnails {
+		urls = append(urls, url)
+	}
+	return urls
+}
+
+// UploadedImage is an image uploaded to the blob storage under Key, with its
+// derivatives if it has any.
+type UploadedImage struct {
+	Key         string
+	Derivatives *ImageDerivatives
+}
+
+type ImageVariant struct {
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	Url    string `json:"url"`
+}
+
+// Value stores the derivatives as JSON.
+func (d *ImageDerivatives) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
+// parseImageDerivatives reads derivatives stored as JSON. NULL columns have
+// no derivatives.
+func parseImageDerivatives(b []byte) (*ImageDerivatives, error) {
+	if b == nil {
+		return nil, nil
+	}
+	var d ImageDerivatives
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,315 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Media is a file uploaded by an author, such as an image referenced from the
+// content of posts.
+type Media struct {
+	Id           int64  `json:"id"`
+	Url          string `json:"url"`
+	Filename     string `json:"filename"`
+	ContentType  string `json:"content_type"`
+	Size         int64  `json:"size"`
+	AuthorUserId string `json:"author_user_id"`
+	CreatedAt    string `json:"created_at"`
+	// Derivatives are the resized versions of images. Other files, and images
+	// that can't be decoded, have none.
+	Derivatives *ImageDerivatives `json:"derivatives"`
+	// StorageKey is where the file is in the blob storage.
+	StorageKey string `json:"-"`
+	// UsedBy are the slugs of the posts whose content references the file.
+	UsedBy []string `json:"used_by"`
+}
+
+type MediaModel struct {
+	DB *sql.DB
+}
+
+// mediaSortColumns maps the sortable fields of media to their column.
+var mediaSortColumns = map[string]string{
+	"createdAt": "created_at",
+	"filename":  "filename",
+	"size":      "size",
+}
+
+// ParseMediaSort parses a sort string and checks that media can be sorted by
+// its field.
+func ParseMediaSort(s string) (Sort, error) {
+	sort, err := ParseSort(s)
+	if err != nil {
+		return Sort{}, err
+	}
+	if _, ok := mediaSortColumns[sort.Field]; !ok {
+		return Sort{}, fmt.Errorf("%w: %s", ErrUnknownSortField, sort.Field)
+	}
+	return sort, nil
+}
+
+const mediaColumns = `id, storage_key, url, filename, content_type, size, author_user_id, created_at, derivatives`
+
+func scanMedia(row scanner) (*Media, error) {
+	var media Media
+	var derivatives []byte
+
+	err := row.Scan(&media.Id, &media.StorageKey, &media.Url, &media.Filename, &media.ContentType,
+		&media.Size, &media.AuthorUserId, &media.CreatedAt, &derivatives)
+	if err != nil {
+		return nil, err
+	}
+
+	media.Derivatives, err = parseImageDerivatives(derivatives)
+	if err != nil {
+		return nil, err
+	}
+
+	return &media, nil
+}
+
+// Page returns a page of the media sorted by opts.Sort, along with the total
+// number of media. Unless authorUserId is empty, only the media uploaded by
+// that author are returned.
+func (m MediaModel) Page(opts PageOptions, authorUserId string) ([]*Media, int, error) {
+	column, ok := mediaSortColumns[opts.Sort.Field]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrUnknownSortField, opts.Sort.Field)
+	}
+
+	where := ""
+	var args []interface{}
+	if authorUserId != "" {
+		where = "WHERE author_user_id = ?"
+		args = append(args, authorUserId)
+	}
+
+	var total int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM media `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// The id breaks ties so that pages don't overlap.
+	direction := opts.Sort.direction()
+	rows, err := m.DB.Query(`
+		SELECT `+mediaColumns+`
+		FROM media
+		`+where+`
+		ORDER BY `+column+` `+direction+`, id `+direction+`
+		LIMIT ? OFFSET ?`, append(args, opts.PageSize, opts.Offset())...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	list := []*Media{}
+	for rows.Next() {
+		media, err := scanMedia(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, media)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if err := m.fillUsedBy(list); err != nil {
+		return nil, 0, err
+	}
+
+	return list, total, nil
+}
+
+func (m MediaModel) Get(id int64) (*Media, error) {
+	media, err := scanMedia(m.DB.QueryRow(`
+		SELECT `+mediaColumns+`
+		FROM media
+		WHERE id = ?`, id))
+	if err != nil {
+		return nil, notFound(err, "media %d not found", id)
+	}
+
+	if err := m.fillUsedBy([]*Media{media}); err != nil {
+		return nil, err
+	}
+
+	return media, nil
+}
+
+// Add records the uploaded file and sets media.Id and media.CreatedAt. Posts
+// already referencing the URL of the file start using it.
+func (m MediaModel) Add(media *Media) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := CurrentTime()
+	res, err := tx.Exec(`
+		INSERT INTO media
+		(storage_key, url, filename, content_type, size, author_user_id, created_at, derivatives)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		media.StorageKey, media.Url, media.Filename, media.ContentType, media.Size, media.AuthorUserId, now, media.Derivatives)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	media.Id = id
+	media.CreatedAt = now
+
+	urls := append([]string{media.Url}, media.Derivatives.urls()...)
+	conditions := make([]string, len(urls))
+	args := []interface{}{media.Id}
+	for i, url := range urls {
+		conditions[i] = "LOCATE(?, content) > 0"
+		args = append(args, url)
+	}
+	_, err = tx.Exec(`
+		INSERT IGNORE INTO posts_media
+		(post_slug, media_id)
+		SELECT slug, ?
+		FROM posts
+		WHERE `+strings.Join(conditions, " OR "), args...)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	media.UsedBy = []string{}
+	return m.fillUsedBy([]*Media{media})
+}
+
+// Delete deletes the media, unless published posts, trashed or not,
+// reference it, in which case it returns a Conflict naming them. The media
+// stays locked until it is gone, so that no post starts using it in between.
+func (m MediaModel) Delete(id int64) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lockedId int64
+	err = tx.QueryRow(`SELECT id FROM media WHERE id = ? FOR UPDATE`, id).Scan(&lockedId)
+	if err != nil {
+		return notFound(err, "media %d not found", id)
+	}
+
+	rows, err := tx.Query(`
+		SELECT posts_media.post_slug
+		FROM posts_media
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts_media.post_slug
+		WHERE posts_media.media_id = ?
+		ORDER BY posts_media.post_slug
+		FOR SHARE`, id)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return err
+		}
+		slugs = append(slugs, slug)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if len(slugs) > 0 {
+		return Conflict("media is used by published posts: %s", strings.Join(slugs, ", "))
+	}
+
+	_, err = tx.Exec(`DELETE FROM media WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_media WHERE media_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// fillUsedBy fills in UsedBy of every media using a single query.
+func (m MediaModel) fillUsedBy(list []*Media) error {
+	if len(list) == 0 {
+		return nil
+	}
+
+	mediaById := make(map[int64]*Media, len(list))
+	args := make([]interface{}, 0, len(list))
+	for _, media := range list {
+		media.UsedBy = []string{}
+		mediaById[media.Id] = media
+		args = append(args, media.Id)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT media_id, post_slug
+		FROM posts_media
+		WHERE media_id IN (`+placeholders(len(args))+`)
+		ORDER BY post_slug`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var slug string
+		if err := rows.Scan(&id, &slug); err != nil {
+			return err
+		}
+		if media, ok := mediaById[id]; ok {
+			media.UsedBy = append(media.UsedBy, slug)
+		}
+	}
+
+	return rows.Err()
+}
+
+// setPostMedia records which media the content of the post references within
+// tx, replacing what was recorded before. Media are referenced by their URL or
+// by the URL of one of their derivatives.
+func setPostMedia(tx *sql.Tx, postSlug string, content string) error {
+	_, err := tx.Exec(`DELETE FROM posts_media WHERE post_slug = ?`, postSlug)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO posts_media
+		(post_slug, media_id)
+		SELECT ?, id
+		FROM media
+		WHERE LOCATE(url, ?) > 0 OR EXISTS (
+			SELECT 1
+			FROM JSON_TABLE(
+				JSON_EXTRACT(media.derivatives, '$.variants[*].url', '$.thumbnails.*'),
+				'$[*]' COLUMNS (url varchar(1000) PATH '$')
+			) AS derivative_urls
+			WHERE LOCATE(derivative_urls.url, ?) > 0
+		)`, postSlug, content, content)
+	return err
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDeleteMediaUsedByPublishedPosts(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+	media := MediaModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	used := &Media{Url: "/uploads/media/used.png", Filename: "used.png", ContentType: "image/png", AuthorUserId: author.UserId, StorageKey: "media/used.png"}
+	drafted := &Media{Url: "/uploads/media/drafted.png", Filename: "drafted.png", ContentType: "image/png", AuthorUserId: author.UserId, StorageKey: "media/drafted.png"}
+	for _, m := range []*Media{used, drafted} {
+		if err := media.Add(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, post := range []*Post{
+		{Slug: "published", Published: true, Content: "![](" + used.Url + ")"},
+		{Slug: "draft", Content: "![](" + drafted.Url + ")"},
+	} {
+		post.Title = post.Slug
+		post.Author = author
+		if err := posts.Add(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := media.Delete(used.Id)
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("deleting media of a published post: got %v, want a conflict", err)
+	}
+	if _, err := media.Get(used.Id); err != nil {
+		t.Errorf("media of a published post is gone: %v", err)
+	}
+
+	if err := media.Delete(drafted.Id); err != nil {
+		t.Errorf("deleting media of a draft: %v", err)
+	}
+	if err := media.Delete(drafted.Id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting deleted media: got %v, want not found", err)
+	}
+}
+
+func TestPostsUseMediaThroughDerivatives(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+	media := MediaModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	variant := &Media{
+		Url: "/uploads/media/variant.png", Filename: "variant.png", ContentType: "image/png", AuthorUserId: author.UserId, StorageKey: "media/variant.png",
+		Derivatives: &ImageDerivatives{
+			Variants:   []ImageVariant{{Width: 640, Height: 360, Url: "/uploads/media/variant-640w.jpg"}},
+			Thumbnails: map[string]string{"image/jpeg": "/uploads/media/variant-thumb.jpg"},
+		},
+	}
+	thumbnail := &Media{
+		Url: "/uploads/media/thumbnail.png", Filename: "thumbnail.png", ContentType: "image/png", AuthorUserId: author.UserId, StorageKey: "media/thumbnail.png",
+		Derivatives: &ImageDerivatives{
+			Variants:   []ImageVariant{},
+			Thumbnails: map[string]string{"image/jpeg": "/uploads/media/thumbnail-thumb.jpg"},
+		},
+	}
+	if err := media.Add(variant); err != nil {
+		t.Fatal(err)
+	}
+	post := &Post{
+		Slug:      "published",
+		Title:     "Published",
+		Published: true,
+		Author:    author,
+		Content:   "![](/uploads/media/variant-640w.jpg)\n\n![](/uploads/media/thumbnail-thumb.jpg)",
+	}
+	if err := posts.Add(post); err != nil {
+		t.Fatal(err)
+	}
+	// Media uploaded after the post is recorded as used too.
+	if err := media.Add(thumbnail); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []*Media{variant, thumbnail} {
+		if err := media.Delete(m.Id); !errors.Is(err, ErrConflict) {
+			t.Errorf("deleting %s referenced through a derivative: got %v, want a conflict", m.Filename, err)
+		}
+	}
+}
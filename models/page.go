@@ -0,0 +1,348 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Page is a standalone page such as About or Uses. Unlike posts, pages are
+// ordered by MenuOrder and have no previous or next page.
+type Page struct {
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Excerpt     string    `json:"excerpt"`
+	Content     string    `json:"content"`
+	MenuOrder   int       `json:"menu_order"`
+	Published   bool      `json:"published"`
+	PublishedAt string    `json:"published_at,omitempty"`
+	ModifiedAt  string    `json:"modified_at"`
+	Author      *Author   `json:"author"`
+	Co_Authors  []*Author `json:"co_authors,omitempty"`
+}
+
+// IsLive reports whether the page is visible to readers.
+func (page *Page) IsLive() bool {
+	return page.Published && page.PublishedAt <= CurrentTime()
+}
+
+func (page *Page) IsAuthor(author *Author) bool {
+	if page.Author != nil && page.Author.UserId == author.UserId {
+		return true
+	}
+	for _, pageAuthor := range page.Co_Authors {
+		if pageAuthor.UserId == author.UserId {
+			return true
+		}
+	}
+	return false
+}
+
+type PageModel struct {
+	DB *sql.DB
+}
+
+// pageSortColumns maps the sortable fields of pages to their column.
+var pageSortColumns = map[string]string{
+	"menuOrder":   "menu_order",
+	"title":       "title",
+	"slug":        "slug",
+	"publishedAt": "published_at",
+	"modifiedAt":  "modified_at",
+}
+
+// ParsePageSort parses a sort string and checks that pages can be sorted by
+// its field.
+func ParsePageSort(s string) (Sort, error) {
+	sort, err := ParseSort(s)
+	if err != nil {
+		return Sort{}, err
+	}
+	if _, ok := pageSortColumns[sort.Field]; !ok {
+		return Sort{}, fmt.Errorf("%w: %s", ErrUnknownSortField, sort.Field)
+	}
+	return sort, nil
+}
+
+// PageFilter narrows down the pages returned by PageModel.All.
+type PageFilter struct {
+	// Drafts and scheduled pages are only listed to their authors, identified
+	// by ViewerUserId, and to admins. An empty ViewerUserId is an anonymous
+	// reader.
+	ViewerUserId  string
+	ViewerIsAdmin bool
+}
+
+// where returns the WHERE clause of the filter and its arguments.
+func (f PageFilter) where() (string, []interface{}) {
+	if f.ViewerIsAdmin {
+		return "", nil
+	}
+
+	condition := `published_at <= ?`
+	args := []interface{}{CurrentTime()}
+	if f.ViewerUserId != "" {
+		condition = `(` + condition + ` OR EXISTS (
+			SELECT 1 FROM pages_authors
+			WHERE pages_authors.page_slug = pages.slug AND pages_authors.author_user_id = ?
+		))`
+		args = append(args, f.ViewerUserId)
+	}
+
+	return "WHERE " + condition, args
+}
+
+const pageColumns = `slug, title, excerpt, content, menu_order, published_at, modified_at`
+
+func scanPage(row scanner) (*Page, error) {
+	var page Page
+	var publishedAt sql.NullString
+
+	err := row.Scan(&page.Slug, &page.Title, &page.Excerpt, &page.Content, &page.MenuOrder, &publishedAt, &page.ModifiedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if publishedAt.Valid {
+		page.Published = true
+		page.PublishedAt = publishedAt.String
+	}
+
+	return &page, nil
+}
+
+// All returns every page matching filter sorted by sort, then by slug.
+func (m PageModel) All(sort Sort, filter PageFilter) ([]*Page, error) {
+	column, ok := pageSortColumns[sort.Field]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSortField, sort.Field)
+	}
+	where, args := filter.where()
+
+	rows, err := m.DB.Query(`
+		SELECT `+pageColumns+`
+		FROM pages
+		`+where+`
+		ORDER BY `+column+` `+sort.direction()+`, slug ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pages := []*Page{}
+	for rows.Next() {
+		page, err := scanPage(rows)
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, page)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.FillAuthorsOfPages(pages); err != nil {
+		return nil, err
+	}
+
+	return pages, nil
+}
+
+func (m PageModel) Get(slug string) (*Page, error) {
+	page, err := scanPage(m.DB.QueryRow(`
+		SELECT `+pageColumns+`
+		FROM pages
+		WHERE slug = ?`, slug))
+	if err != nil {
+		return nil, notFound(err, "page %s not found", slug)
+	}
+
+	if err := m.FillAuthorsOfPages([]*Page{page}); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (m PageModel) Add(page *Page) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if page.Published && page.PublishedAt == "" {
+		page.PublishedAt = CurrentTime()
+	}
+
+	now := CurrentTime()
+	_, err = tx.Exec(`
+		INSERT INTO pages
+		(slug, title, excerpt, content, menu_order, published_at, modified_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		page.Slug, page.Title, page.Excerpt, page.Content, page.MenuOrder, nullablePublishedAt(page), now)
+	if err != nil {
+		return duplicate(err, "page %s already exists", page.Slug)
+	}
+	page.ModifiedAt = now
+
+	if err := setPageAuthors(tx, page); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Update updates the page and also modifies newPage as the new page is in the
+// database. The authors of the page are replaced by newPage's.
+func (m PageModel) Update(newPage *Page) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !newPage.Published {
+		newPage.PublishedAt = ""
+	} else if newPage.PublishedAt == "" {
+		newPage.PublishedAt = CurrentTime()
+	}
+
+	now := CurrentTime()
+	_, err = tx.Exec(`
+		UPDATE pages
+		SET title=?, excerpt=?, content=?, menu_order=?, published_at=?, modified_at=?
+		WHERE slug=?`,
+		newPage.Title, newPage.Excerpt, newPage.Content, newPage.MenuOrder, nullablePublishedAt(newPage), now, newPage.Slug)
+	if err != nil {
+		return err
+	}
+	newPage.ModifiedAt = now
+
+	if err := setPageAuthors(tx, newPage); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m PageModel) Delete(slug string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM pages WHERE slug = ?`, slug)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return NotFound("page %s not found", slug)
+	}
+
+	_, err = tx.Exec(`DELETE FROM pages_authors WHERE page_slug = ?`, slug)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FillAuthorsOfPages fills in Author and Co_Authors of every page using a
+// single query.
+func (m PageModel) FillAuthorsOfPages(pages []*Page) error {
+	if len(pages) == 0 {
+		return nil
+	}
+
+	pagesBySlug := make(map[string]*Page, len(pages))
+	args := make([]interface{}, 0, len(pages))
+	for _, page := range pages {
+		page.Author = nil
+		page.Co_Authors = nil
+		pagesBySlug[page.Slug] = page
+		args = append(args, page.Slug)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT pages_authors.page_slug, user_id, full_name, email, bio, pages_authors.is_original
+		FROM authors
+		INNER JOIN pages_authors ON pages_authors.author_user_id = authors.user_id
+		WHERE pages_authors.page_slug IN (`+placeholders(len(args))+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug string
+		var author Author
+		var isOriginal bool
+		err := rows.Scan(&slug, &author.UserId, &author.FullName, &author.Email, &author.Bio, &isOriginal)
+		if err != nil {
+			return err
+		}
+
+		page, ok := pagesBySlug[slug]
+		if !ok {
+			continue
+		}
+		if isOriginal {
+			page.Author = &author
+			continue
+		}
+		page.Co_Authors = append(page.Co_Authors, &author)
+	}
+
+	return rows.Err()
+}
+
+// setPageAuthors replaces the authors of the page within tx.
+func setPageAuthors(tx *sql.Tx, page *Page) error {
+	_, err := tx.Exec(`DELETE FROM pages_authors WHERE page_slug = ?`, page.Slug)
+	if err != nil {
+		return err
+	}
+
+	addAuthorStmt, err := tx.Prepare(`
+		INSERT INTO pages_authors
+		(page_slug, author_user_id, is_original)
+		VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer addAuthorStmt.Close()
+
+	for _, author := range page.Co_Authors {
+		// In case the original author is in Co_Authors slice, we ignore.
+		if author.UserId == page.Author.UserId {
+			continue
+		}
+
+		_, err := addAuthorStmt.Exec(page.Slug, author.UserId, 0)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = addAuthorStmt.Exec(page.Slug, page.Author.UserId, 1)
+	return err
+}
+
+func nullablePublishedAt(page *Page) sql.NullString {
+	return sql.NullString{String: page.PublishedAt, Valid: page.Published}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownSortField = errors.New("unknown sort field")
+
+// Sort describes the order of a list query, e.g. "publishedAt_DESC".
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a sort string in the form of fieldName_ASC or
+// fieldName_DESC. It does not check whether the field is sortable.
+func ParseSort(s string) (Sort, error) {
+	idx := strings.LastIndex(s, "_")
+	if idx <= 0 {
+		return Sort{}, fmt.Errorf("sort must be in the format of fieldName_ASC or fieldName_DESC")
+	}
+
+	sort := Sort{Field: s[:idx]}
+	switch s[idx+1:] {
+	case "ASC":
+	case "DESC":
+		sort.Desc = true
+	default:
+		return Sort{}, fmt.Errorf("sort must be in the format of fieldName_ASC or fieldName_DESC")
+	}
+
+	return sort, nil
+}
+
+func (s Sort) String() string {
+	return s.Field + "_" + s.direction()
+}
+
+func (s Sort) direction() string {
+	if s.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// PageOptions describes which page of a list query to return.
+type PageOptions struct {
+	Page     int
+	PageSize int
+	Sort     Sort
+}
+
+func (o PageOptions) Offset() int {
+	return (o.Page - 1) * o.PageSize
+}
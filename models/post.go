@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 )
 
 type Post struct {
@@ -16,6 +18,20 @@ type Post struct {
 	Author      *Author   `json:"author"`
 	Co_Authors  []*Author `json:"co_authors,omitempty"`
 	CoverUrl    *string   `json:"cover_url"`
+	// CoverImages are the derivatives of the cover, if it was uploaded.
+	CoverImages *ImageDerivatives `json:"cover_images"`
+	Tags        []string          `json:"tags"`
+	// Scheduled posts are published, but not visible to readers until
+	// PublishedAt.
+	Scheduled bool `json:"scheduled"`
+	// Version is incremented by every update, so that updates based on an
+	// outdated version can be rejected.
+	Version int `json:"version"`
+}
+
+// IsLive reports whether the post is visible to readers.
+func (post *Post) IsLive() bool {
+	return post.Published && !post.Scheduled
 }
 
 func (post *Post) IsAuthor(author *Author) bool {
@@ -34,129 +50,284 @@ type PostModel struct {
 	DB *sql.DB
 }
 
-func (m PostModel) All() ([]*Post, error) {
-	// Fetch all posts' information
-	rows, err := m.DB.Query(`
-		SELECT slug, title, excerpt, content, modified_at
-		FROM posts`)
+// notTrashed is the condition of posts that are not in the trash. The query
+// must select from posts.
+const notTrashed = `NOT EXISTS (
+	SELECT 1 FROM posts_trash WHERE posts_trash.post_slug = posts.slug
+)`
+
+// postSortColumns maps the sortable fields of a post to their columns.
+var postSortColumns = map[string]string{
+	"publishedAt": "posts_publication.published_at",
+	"modifiedAt":  "posts.modified_at",
+	"title":       "posts.title",
+	"slug":        "posts.slug",
+}
+
+// ParsePostSort parses a sort string and checks that the field is sortable.
+func ParsePostSort(s string) (Sort, error) {
+	sort, err := ParseSort(s)
 	if err != nil {
-		return nil, err
+		return Sort{}, err
+	}
+	if _, ok := postSortColumns[sort.Field]; !ok {
+		return Sort{}, fmt.Errorf("%w: %s", ErrUnknownSortField, sort.Field)
+	}
+	return sort, nil
+}
+
+// PostFilter narrows down the posts returned by Page. The zero value matches
+// every post visible to an anonymous reader.
+type PostFilter struct {
+	// Tag only matches posts tagged with this tag slug.
+	Tag string
+	// Author only matches posts this user is an author of.
+	Author string
+	// Drafts and scheduled posts are only listed to their authors, identified
+	// by ViewerUserId, and to admins. An empty ViewerUserId is an anonymous
+	// reader.
+	ViewerUserId  string
+	ViewerIsAdmin bool
+}
+
+// where returns the WHERE clause of the filter and its arguments. The query
+// must select from posts.
+func (f PostFilter) where() (string, []interface{}) {
+	conditions := []string{notTrashed}
+	var args []interface{}
+
+	if f.Tag != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM posts_tags
+			WHERE posts_tags.post_slug = posts.slug AND posts_tags.tag_slug = ?
+		)`)
+		args = append(args, f.Tag)
+	}
+
+	if f.Author != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM posts_authors
+			WHERE posts_authors.post_slug = posts.slug AND posts_authors.author_user_id = ?
+		)`)
+		args = append(args, f.Author)
+	}
+
+	if !f.ViewerIsAdmin {
+		condition := `EXISTS (
+			SELECT 1 FROM posts_publication
+			WHERE posts_publication.post_slug = posts.slug AND posts_publication.published_at <= ?
+		)`
+		args = append(args, CurrentTime())
+		if f.ViewerUserId != "" {
+			condition = `(` + condition + ` OR EXISTS (
+				SELECT 1 FROM posts_authors
+				WHERE posts_authors.post_slug = posts.slug AND posts_authors.author_user_id = ?
+			))`
+			args = append(args, f.ViewerUserId)
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// Page returns a page of the posts matching filter sorted by opts.Sort, along
+// with the total number of matching posts. Only the given fields are selected.
+func (m PostModel) Page(opts PageOptions, filter PostFilter, fields PostFields) ([]*Post, int, error) {
+	column, ok := postSortColumns[opts.Sort.Field]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrUnknownSortField, opts.Sort.Field)
 	}
-	defer rows.Close()
 
-	publishedRows, err := m.DB.Query(`
-		SELECT post_slug, published_at
-		FROM posts_publication`)
+	where, args := filter.where()
+
+	var total int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM posts `+where, args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer publishedRows.Close()
 
-	publications := make(map[string]string)
-	for publishedRows.Next() {
-		var slug string
-		var timeBytes []byte
-		err := publishedRows.Scan(&slug, &timeBytes)
+	// The slug breaks ties so that pages don't overlap.
+	direction := opts.Sort.direction()
+	rows, err := m.DB.Query(`
+		SELECT `+postColumns(fields)+`
+		FROM posts
+		LEFT JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		`+where+`
+		ORDER BY `+column+` `+direction+`, posts.slug `+direction+`
+		LIMIT ? OFFSET ?`, append(args, opts.PageSize, opts.Offset())...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	posts := []*Post{}
+	for rows.Next() {
+		post, err := scanPost(rows, fields)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
-		publications[slug] = string(timeBytes)
+
+		posts = append(posts, post)
 	}
-	if err = publishedRows.Err(); err != nil {
-		return nil, err
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if fields.hasAuthors() {
+		if err := m.FillAuthorsOfPosts(posts); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if fields.Has("tags") {
+		if err := m.FillTagsOfPosts(posts); err != nil {
+			return nil, 0, err
+		}
 	}
 
-	coverUrlRows, err := m.DB.Query(`
-		SELECT post_slug, cover_url
-		FROM posts_cover_url`)
+	return posts, total, nil
+}
+
+// PostCursor is a position in the list of published posts, ordered by
+// published_at then slug, newest first.
+type PostCursor struct {
+	PublishedAt string
+	Slug        string
+}
+
+// After returns up to limit published posts that come after cursor, newest
+// first. A nil cursor starts from the newest post. Unlike Page, posts published
+// in the meantime don't shift the following pages. Scheduled posts are left
+// out. Only the given fields are selected.
+func (m PostModel) After(cursor *PostCursor, limit int, fields PostFields) ([]*Post, error) {
+	where := "WHERE posts_publication.published_at <= ? AND " + notTrashed
+	args := []interface{}{CurrentTime()}
+	if cursor != nil {
+		where += `
+			AND (posts_publication.published_at < ?
+				OR (posts_publication.published_at = ? AND posts.slug < ?))`
+		args = append(args, cursor.PublishedAt, cursor.PublishedAt, cursor.Slug)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT `+postColumns(fields)+`
+		FROM posts
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		`+where+`
+		ORDER BY posts_publication.published_at DESC, posts.slug DESC
+		LIMIT ?`, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
-	defer publishedRows.Close()
+	defer rows.Close()
 
-	coverUrls := make(map[string]string)
-	for coverUrlRows.Next() {
-		var slug string
-		var coverUrl string
-		err := coverUrlRows.Scan(&slug, &coverUrl)
+	posts := []*Post{}
+	for rows.Next() {
+		post, err := scanPost(rows, fields)
 		if err != nil {
 			return nil, err
 		}
-		coverUrls[slug] = coverUrl
+
+		posts = append(posts, post)
 	}
-	if err = coverUrlRows.Err(); err != nil {
+
+	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	// Populating a list of posts
-	var posts []*Post
-
-	for rows.Next() {
-		var post Post
-
-		err := rows.Scan(&post.Slug, &post.Title, &post.Excerpt, &post.Content, &post.ModifiedAt)
-		if err != nil {
+	if fields.hasAuthors() {
+		if err := m.FillAuthorsOfPosts(posts); err != nil {
 			return nil, err
 		}
+	}
 
-		if publishedAt, ok := publications[post.Slug]; ok {
-			post.Published = true
-			post.PublishedAt = publishedAt
+	if fields.Has("tags") {
+		if err := m.FillTagsOfPosts(posts); err != nil {
+			return nil, err
 		}
+	}
+
+	return posts, nil
+}
 
-		m.FillAuthors(&post)
+// scanPost scans a row of the columns returned by postColumns into a Post.
+func scanPost(row scanner, fields PostFields) (*Post, error) {
+	var post Post
+	var publishedAt, coverUrl sql.NullString
+	var coverImages []byte
 
-		if coverUrl, ok := coverUrls[post.Slug]; ok {
-			post.CoverUrl = &coverUrl
+	dest := []interface{}{&post.Slug}
+	for _, c := range optionalPostColumns {
+		if fields.selects(c.field) {
+			dest = append(dest, c.dest(&post))
 		}
+	}
+	dest = append(dest, &publishedAt, &coverUrl, &coverImages)
 
-		posts = append(posts, &post)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
+	if publishedAt.Valid {
+		post.Published = true
+		post.PublishedAt = publishedAt.String
+		post.Scheduled = post.PublishedAt > CurrentTime()
+	}
+	if coverUrl.Valid {
+		post.CoverUrl = &coverUrl.String
+	}
+	derivatives, err := parseImageDerivatives(coverImages)
+	if err != nil {
 		return nil, err
 	}
+	post.CoverImages = derivatives
 
-	return posts, nil
+	return &post, nil
 }
 
 func (m PostModel) Get(slug string) (*Post, error) {
-	var post Post = Post{Slug: slug}
+	return m.GetFields(slug, AllPostFields)
+}
 
-	err := m.DB.QueryRow(`
-		SELECT title, excerpt, content, modified_at
+// GetFields returns the post with only the given fields selected. Trashed
+// posts are not found.
+func (m PostModel) GetFields(slug string, fields PostFields) (*Post, error) {
+	post, err := scanPost(m.DB.QueryRow(`
+		SELECT `+postColumns(fields)+`
 		FROM posts
-		WHERE slug = ?`, slug).Scan(&post.Title, &post.Excerpt, &post.Content, &post.ModifiedAt)
+		LEFT JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		WHERE posts.slug = ? AND `+notTrashed, slug), fields)
 	if err != nil {
-		return nil, err
+		return nil, notFound(err, "post %s not found", slug)
 	}
 
-	var timeBytes []byte
-	err = m.DB.QueryRow(`
-		SELECT published_at
-		FROM posts_publication
-		WHERE post_slug = ?`, slug).Scan(&timeBytes)
-	if err == nil {
-		post.Published = true
-		post.PublishedAt = string(timeBytes)
-	} else if err != sql.ErrNoRows {
+	if err := m.fill(post, fields); err != nil {
 		return nil, err
 	}
 
-	m.FillAuthors(&post)
+	return post, nil
+}
 
-	var coverUrl string
-	err = m.DB.QueryRow(`
-		SELECT cover_url
-		FROM posts_cover_url
-		WHERE post_slug = ?`, slug).Scan(&coverUrl)
-	if err == nil {
-		post.CoverUrl = &coverUrl
-	} else if err != sql.ErrNoRows {
-		return nil, err
+// fill fills in the authors and tags of the post if fields has them.
+func (m PostModel) fill(post *Post, fields PostFields) error {
+	if fields.hasAuthors() {
+		if err := m.FillAuthors(post); err != nil {
+			return err
+		}
 	}
 
-	return &post, nil
+	if fields.Has("tags") {
+		if err := m.FillTagsOfPosts([]*Post{post}); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (m PostModel) Add(post *Post) error {
@@ -166,10 +337,22 @@ func (m PostModel) Add(post *Post) error {
 	}
 	defer tx.Rollback()
 
+	now := CurrentTime()
 	_, err = tx.Exec(`
 		INSERT INTO posts
 		(slug, title, excerpt, content, modified_at)
-		VALUES (?, ?, ?, ?, ?)`, post.Slug, post.Title, post.Excerpt, post.Content, CurrentTime())
+		VALUES (?, ?, ?, ?, ?)`, post.Slug, post.Title, post.Excerpt, post.Content, now)
+	if err != nil {
+		return slugTaken(tx, err, post.Slug)
+	}
+	post.Version = 1
+
+	if err := addRevision(tx, post, post.Author.UserId, now); err != nil {
+		return err
+	}
+
+	// A new post taking the former slug of another one stops it redirecting.
+	_, err = tx.Exec(`DELETE FROM posts_redirects WHERE old_slug = ?`, post.Slug)
 	if err != nil {
 		return err
 	}
@@ -217,37 +400,88 @@ func (m PostModel) Add(post *Post) error {
 		return err
 	}
 
+	if err := setPostTags(tx, post.Slug, post.Tags); err != nil {
+		return err
+	}
+
+	if err := setPostMedia(tx, post.Slug, post.Content); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return err
 	}
 	return nil
 }
 
-// Update updates the post and also modifies newPost as the new post is in the
-// database.
-func (m PostModel) Update(newPost *Post) error {
-	post, err := m.Get(newPost.Slug)
+// Update updates the post of the given slug and also modifies newPost as the
+// new post is in the database. The authors and tags of the post are replaced by
+// newPost's, and so is the cover, which is removed if newPost has none. If
+// newPost.Slug differs from slug, the post is renamed and the old slug
+// redirects to the new one. A revision edited by editorUserId is recorded along
+// with the update. Unless version is 0, the post must still be at that version,
+// or ErrStaleVersion is returned. If the update replaces or removes an uploaded
+// cover, Update returns it for the caller to delete from the blob storage.
+func (m PostModel) Update(slug string, newPost *Post, editorUserId string, version int) (*UploadedImage, error) {
+	tx, err := m.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer tx.Rollback()
 
-	tx, err := m.DB.Begin()
+	// The current state of the post is read once it's locked, so that
+	// concurrent updates apply one after the other.
+	version, err = lockVersion(tx, version, `SELECT version FROM posts WHERE slug = ? AND `+notTrashed, slug)
 	if err != nil {
-		return err
+		return nil, notFound(err, "post %s not found", slug)
+	}
+	var originalAuthorId string
+	var published bool
+	err = tx.QueryRow(`
+		SELECT
+			IFNULL((SELECT author_user_id FROM posts_authors WHERE post_slug = ? AND is_original), ''),
+			EXISTS (SELECT 1 FROM posts_publication WHERE post_slug = ?)`,
+		slug, slug).Scan(&originalAuthorId, &published)
+	if err != nil {
+		return nil, err
+	}
+
+	// Posts written before revisions existed have none, so their current state
+	// is kept first as if the original author wrote it.
+	_, err = tx.Exec(`
+		INSERT INTO posts_revisions
+		(post_slug, title, excerpt, content, editor_user_id, created_at)
+		SELECT slug, title, excerpt, content, ?, modified_at
+		FROM posts
+		WHERE slug = ? AND NOT EXISTS (
+			SELECT 1 FROM posts_revisions WHERE post_slug = ?
+		)`, originalAuthorId, slug, slug)
+	if err != nil {
+		return nil, err
 	}
-	defer tx.Rollback()
 
 	now := CurrentTime()
+	if newPost.Slug != slug {
+		if err := renamePost(tx, slug, newPost.Slug, now); err != nil {
+			return nil, err
+		}
+	}
+
 	_, err = tx.Exec(`
 		UPDATE posts
-		SET title=?, excerpt=?, content=?, modified_at=?
+		SET title=?, excerpt=?, content=?, modified_at=?, version=version+1
 		WHERE slug=?`, newPost.Title, newPost.Excerpt, newPost.Content, now, newPost.Slug)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	newPost.ModifiedAt = now
+	newPost.Version = version + 1
 
-	if !post.Published && newPost.Published {
+	if err := addRevision(tx, newPost, editorUserId, now); err != nil {
+		return nil, err
+	}
+
+	if !published && newPost.Published {
 		if newPost.PublishedAt == "" {
 			newPost.PublishedAt = CurrentTime()
 		}
@@ -256,40 +490,55 @@ func (m PostModel) Update(newPost *Post) error {
 			(post_slug, published_at)
 			VALUES (?, ?)`, newPost.Slug, newPost.PublishedAt)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	} else if post.Published && !newPost.Published {
+	} else if published && !newPost.Published {
 		_, err := tx.Exec(`DELETE FROM posts_publication WHERE post_slug=?`, newPost.Slug)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		newPost.Published = false
 		newPost.PublishedAt = ""
 	} else if newPost.Published && newPost.PublishedAt != "" {
+		// Rescheduling to the future announces the post again once it's live.
 		_, err := tx.Exec(`
 			UPDATE posts_publication
-			SET published_at = ?
-			WHERE post_slug = ?`, newPost.PublishedAt, newPost.Slug)
+			SET announced_at = IF(? > ?, NULL, announced_at), published_at = ?
+			WHERE post_slug = ?`, newPost.PublishedAt, now, newPost.PublishedAt, newPost.Slug)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
+	coverUrl, cover, err := lockCover(tx, newPost.Slug)
+	if err != nil {
+		return nil, err
+	}
+	var replacedCover *UploadedImage
+	if newPost.CoverUrl == nil || *newPost.CoverUrl != coverUrl {
+		replacedCover = cover
+	}
+
 	if newPost.CoverUrl != nil {
 		_, err = tx.Exec(`
 			INSERT INTO posts_cover_url
 			(post_slug, cover_url)
 			VALUES (?, ?)
-			ON DUPLICATE KEY UPDATE cover_url = ?
-			`, newPost.Slug, *newPost.CoverUrl, *newPost.CoverUrl)
-		if err != nil {
-			return err
-		}
+			ON DUPLICATE KEY UPDATE
+				cover_key = IF(cover_url = ?, cover_key, NULL),
+				derivatives = IF(cover_url = ?, derivatives, NULL),
+				cover_url = ?
+			`, newPost.Slug, *newPost.CoverUrl, *newPost.CoverUrl, *newPost.CoverUrl, *newPost.CoverUrl)
+	} else {
+		_, err = tx.Exec(`DELETE FROM posts_cover_url WHERE post_slug = ?`, newPost.Slug)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	_, err = tx.Exec(`DELETE FROM posts_authors WHERE post_slug = ?`, newPost.Slug)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	addAuthorStmt, err := tx.Prepare(`
@@ -297,7 +546,7 @@ func (m PostModel) Update(newPost *Post) error {
 		(post_slug, author_user_id, is_original)
 		VALUES (?, ?, ?)`)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer addAuthorStmt.Close()
 
@@ -309,60 +558,180 @@ func (m PostModel) Update(newPost *Post) error {
 
 		_, err := addAuthorStmt.Exec(newPost.Slug, author.UserId, 0)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 	_, err = addAuthorStmt.Exec(newPost.Slug, newPost.Author.UserId, 1)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err := setPostTags(tx, newPost.Slug, newPost.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := setPostMedia(tx, newPost.Slug, newPost.Content); err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return replacedCover, nil
 }
 
-func (m PostModel) Delete(slug string) error {
+// SetCover sets the cover of the post to the image uploaded under key to
+// coverUrl, with its derivatives if it has any. It returns the uploaded cover
+// it replaces, if any, for the caller to delete from the blob storage.
+func (m PostModel) SetCover(slug string, coverUrl string, key string, derivatives *ImageDerivatives) (*UploadedImage, error) {
 	tx, err := m.DB.Begin()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, cover, err := lockCover(tx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO posts_cover_url
+		(post_slug, cover_url, cover_key, derivatives)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE cover_url = ?, cover_key = ?, derivatives = ?`,
+		slug, coverUrl, key, derivatives, coverUrl, key, derivatives)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return cover, nil
+}
+
+// lockCover locks the cover of the post and returns its URL, and the image it
+// was uploaded as, if it was. The URL is empty if the post has no cover.
+func lockCover(tx *sql.Tx, slug string) (string, *UploadedImage, error) {
+	var coverUrl string
+	var coverKey sql.NullString
+	var derivatives []byte
+	err := tx.QueryRow(`
+		SELECT cover_url, cover_key, derivatives
+		FROM posts_cover_url
+		WHERE post_slug = ?
+		FOR UPDATE`, slug).Scan(&coverUrl, &coverKey, &derivatives)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if !coverKey.Valid {
+		return coverUrl, nil, nil
+	}
+
+	cover := &UploadedImage{Key: coverKey.String}
+	if cover.Derivatives, err = parseImageDerivatives(derivatives); err != nil {
+		return "", nil, err
+	}
+	return coverUrl, cover, nil
+}
+
+// Delete permanently deletes the post. See Trash for deleting it reversibly.
+// It returns the uploaded cover of the post, if it has one, for the caller to
+// delete from the blob storage once the post is gone.
+func (m PostModel) Delete(slug string) (*UploadedImage, error) {
+	return m.delete(slug, false)
+}
+
+// delete permanently deletes the post. If trashed, the post must still be in
+// the trash when it is deleted, or ErrNotFound is returned.
+func (m PostModel) delete(slug string, trashed bool) (*UploadedImage, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
 	}
 	defer tx.Rollback()
 
+	// Restoring the post deletes its row of the trash, so locking the row
+	// keeps the post from being restored before it's deleted.
+	if trashed {
+		err := tx.QueryRow(`
+			SELECT post_slug
+			FROM posts_trash
+			WHERE post_slug = ?
+			FOR UPDATE`, slug).Scan(&slug)
+		if err != nil {
+			return nil, notFound(err, "post %s not found in the trash", slug)
+		}
+	}
+
+	_, cover, err := lockCover(tx, slug)
+	if err != nil {
+		return nil, err
+	}
+
 	res, err := tx.Exec(`DELETE FROM posts WHERE slug = ?`, slug)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	rows, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if rows == 0 {
-		return errors.New("no post were deleted")
+		return nil, NotFound("post %s not found", slug)
 	}
 
 	_, err = tx.Exec(`DELETE FROM posts_publication WHERE post_slug = ?`, slug)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = tx.Exec(`DELETE FROM posts_cover_url WHERE post_slug = ?`, slug)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = tx.Exec(`DELETE FROM posts_authors WHERE post_slug = ?`, slug)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_tags WHERE post_slug = ?`, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_revisions WHERE post_slug = ?`, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_trash WHERE post_slug = ?`, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_redirects WHERE post_slug = ?`, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_media WHERE post_slug = ?`, slug)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := tx.Commit(); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return cover, nil
 }
 
 // FillAuthors fills in post.Author and post.Authors
@@ -399,33 +768,153 @@ func (m PostModel) FillAuthors(post *Post) error {
 	return nil
 }
 
-// TODO: might have non-repeatable read problem here, because in-between these 2
-// queries, the data in the table might change.
+// FillAuthorsOfPosts fills in Author and Co_Authors of every post using a
+// single query.
+func (m PostModel) FillAuthorsOfPosts(posts []*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postsBySlug := make(map[string]*Post, len(posts))
+	args := make([]interface{}, 0, len(posts))
+	for _, post := range posts {
+		post.Author = nil
+		post.Co_Authors = nil
+		postsBySlug[post.Slug] = post
+		args = append(args, post.Slug)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT posts_authors.post_slug, user_id, full_name, email, bio, posts_authors.is_original
+		FROM authors
+		INNER JOIN posts_authors ON posts_authors.author_user_id = authors.user_id
+		WHERE posts_authors.post_slug IN (`+placeholders(len(args))+`)`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug string
+		var author Author
+		var isOriginal bool
+		err := rows.Scan(&slug, &author.UserId, &author.FullName, &author.Email, &author.Bio, &isOriginal)
+		if err != nil {
+			return err
+		}
+
+		post, ok := postsBySlug[slug]
+		if !ok {
+			continue
+		}
+		if isOriginal {
+			post.Author = &author
+			continue
+		}
+		post.Co_Authors = append(post.Co_Authors, &author)
+	}
+
+	return rows.Err()
+}
+
+// FillTagsOfPosts fills in Tags of every post using a single query.
+func (m PostModel) FillTagsOfPosts(posts []*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postsBySlug := make(map[string]*Post, len(posts))
+	args := make([]interface{}, 0, len(posts))
+	for _, post := range posts {
+		post.Tags = []string{}
+		postsBySlug[post.Slug] = post
+		args = append(args, post.Slug)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT post_slug, tag_slug
+		FROM posts_tags
+		WHERE post_slug IN (`+placeholders(len(args))+`)
+		ORDER BY tag_slug`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug, tag string
+		if err := rows.Scan(&slug, &tag); err != nil {
+			return err
+		}
+
+		if post, ok := postsBySlug[slug]; ok {
+			post.Tags = append(post.Tags, tag)
+		}
+	}
+
+	return rows.Err()
+}
+
+// PostNeighbours holds the slugs of the published posts right before and right
+// after a post. Either slug is empty if there is no such post.
+type PostNeighbours struct {
+	Last string
+	Next string
+}
+
+// GetLastAndNextPostSlugs returns the neighbours of every published post in
+// slugs, using a single query. Unpublished and scheduled posts are absent from
+// the result, and are never anyone's neighbour.
+func (m PostModel) GetLastAndNextPostSlugs(slugs []string) (map[string]PostNeighbours, error) {
+	neighbours := make(map[string]PostNeighbours, len(slugs))
+	if len(slugs) == 0 {
+		return neighbours, nil
+	}
+
+	args := make([]interface{}, 0, len(slugs))
+	for _, slug := range slugs {
+		args = append(args, slug)
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT post_slug, last_slug, next_slug
+		FROM (
+			SELECT post_slug,
+				LAG(post_slug) OVER w AS last_slug,
+				LEAD(post_slug) OVER w AS next_slug
+			FROM posts_publication
+			WHERE published_at <= ?
+				AND post_slug NOT IN (SELECT post_slug FROM posts_trash)
+			WINDOW w AS (ORDER BY published_at, post_slug)
+		) AS neighbours
+		WHERE post_slug IN (`+placeholders(len(args))+`)`, append([]interface{}{CurrentTime()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var slug string
+		var last, next sql.NullString
+		if err := rows.Scan(&slug, &last, &next); err != nil {
+			return nil, err
+		}
+		neighbours[slug] = PostNeighbours{Last: last.String, Next: next.String}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return neighbours, nil
+}
+
 func (m PostModel) GetLastAndNextPostSlug(slug string) (last string, next string, err error) {
-	err = m.DB.QueryRow(`
-		SELECT post_slug
-		FROM posts_publication
-		WHERE published_at < (
-			SELECT published_at FROM posts_publication WHERE post_slug = ?
-		)
-		ORDER BY published_at DESC
-		LIMIT 1`, slug).Scan(&last)
-	if err != nil && err != sql.ErrNoRows {
-		return
-	}
-
-	err = m.DB.QueryRow(`
-		SELECT post_slug
-		FROM posts_publication
-		WHERE published_at > (
-			SELECT published_at FROM posts_publication WHERE post_slug = ?
-		)
-		ORDER BY published_at ASC
-		LIMIT 1`, slug).Scan(&next)
-	if err != nil && err != sql.ErrNoRows {
-		return
-	}
-
-	err = nil
-	return
+	neighbours, err := m.GetLastAndNextPostSlugs([]string{slug})
+	if err != nil {
+		return "", "", err
+	}
+
+	n := neighbours[slug]
+	return n.Last, n.Next, nil
 }
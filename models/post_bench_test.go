@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"hxann.com/blog/constants"
+)
+
+// seedPosts adds n published posts, each with an original author, a
+// co-author and two tags.
+func seedPosts(b *testing.B, posts PostModel, authors AuthorModel, n int) {
+	b.Helper()
+
+	original := &Author{UserId: "original", FullName: "Original", Email: "original@example.com"}
+	coAuthor := &Author{UserId: "co-author", FullName: "Co-author", Email: "co-author@example.com"}
+	for _, author := range []*Author{original, coAuthor} {
+		if err := authors.Add(author); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < n; i++ {
+		post := &Post{
+			Slug:        fmt.Sprintf("post-%d", i),
+			Title:       fmt.Sprintf("Post %d", i),
+			Excerpt:     "An excerpt.",
+			Content:     "Some content.",
+			Published:   true,
+			PublishedAt: start.Add(time.Duration(i) * time.Hour).Format(constants.PublishedAtFormat),
+			Author:      original,
+			Co_Authors:  []*Author{coAuthor},
+			Tags:        []string{"go", "mysql"},
+		}
+		if err := posts.Add(post); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPostList compares listing a page of posts with their authors and
+// neighbours in batches to doing so post by post. The batched list issues the
+// same number of queries whatever the size of the page. It needs a MySQL
+// database in $TEST_DSN.
+func BenchmarkPostList(b *testing.B) {
+	db := testDB(b)
+	posts := PostModel{DB: db}
+	seedPosts(b, posts, AuthorModel{DB: db}, 200)
+
+	for _, size := range []int{10, 50, 100} {
+		opts := PageOptions{Page: 1, PageSize: size, Sort: Sort{Field: "publishedAt", Desc: true}}
+
+		b.Run(fmt.Sprintf("batched/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				list, _, err := posts.Page(opts, PostFilter{}, PostSummaryFields)
+				if err != nil {
+					b.Fatal(err)
+				}
+				slugs := make([]string, 0, len(list))
+				for _, post := range list {
+					slugs = append(slugs, post.Slug)
+				}
+				if _, err := posts.GetLastAndNextPostSlugs(slugs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("per-post/%d", size), func(b *testing.B) {
+			fields := PostSummaryFields.Without("author", "co_authors")
+			for i := 0; i < b.N; i++ {
+				list, _, err := posts.Page(opts, PostFilter{}, fields)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for _, post := range list {
+					if err := posts.FillAuthors(post); err != nil {
+						b.Fatal(err)
+					}
+					if _, _, err := posts.GetLastAndNextPostSlug(post.Slug); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PostFields is a set of post fields, named after their JSON keys, used to
+// select only part of a post. The slug is always selected.
+type PostFields map[string]struct{}
+
+// postFieldNames lists every field that can be selected, including the ones
+// that are only computed when responding, such as next_post_slug.
+var postFieldNames = []string{
+	"slug",
+	"title",
+	"excerpt",
+	"content",
+	"published",
+	"published_at",
+	"scheduled",
+	"modified_at",
+	"version",
+	"author",
+	"co_authors",
+	"cover_url",
+	"cover_images",
+	"tags",
+	"last_post_slug",
+	"next_post_slug",
+	"word_count",
+	"reading_time",
+	"content_html",
+	"toc",
+}
+
+// HTMLPostFields are the fields of the content rendered to HTML. They are
+// only sent on demand.
+var HTMLPostFields = []string{"content_html", "toc"}
+
+// knownPostFields are the fields that can be selected.
+var knownPostFields = NewPostFields(postFieldNames...)
+
+// AllPostFields selects every field of a post but HTMLPostFields.
+var AllPostFields = knownPostFields.Without(HTMLPostFields...)
+
+// PostSummaryFields selects every field of a post but its content, and what
+// is computed from it, which is what list views need.
+var PostSummaryFields = AllPostFields.Without("content", "word_count", "reading_time")
+
+func NewPostFields(names ...string) PostFields {
+	fields := PostFields{"slug": {}}
+	for _, name := range names {
+		fields[name] = struct{}{}
+	}
+	return fields
+}
+
+// ParsePostFields parses a comma-separated list of fields, e.g.
+// "title,excerpt,author".
+func ParsePostFields(s string) (PostFields, error) {
+	fields := NewPostFields()
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !knownPostFields.Has(name) {
+			return nil, fmt.Errorf("unknown field: %s", name)
+		}
+		fields[name] = struct{}{}
+	}
+	return fields, nil
+}
+
+func (f PostFields) Has(name string) bool {
+	_, ok := f[name]
+	return ok
+}
+
+// Without returns a copy of f without the given fields.
+func (f PostFields) Without(names ...string) PostFields {
+	fields := PostFields{}
+	for name := range f {
+		fields[name] = struct{}{}
+	}
+	for _, name := range names {
+		delete(fields, name)
+	}
+	return fields
+}
+
+// With returns a copy of f with the given fields added.
+func (f PostFields) With(names ...string) PostFields {
+	fields := f.Without()
+	for _, name := range names {
+		fields[name] = struct{}{}
+	}
+	return fields
+}
+
+// hasAuthors reports whether the authors of the post need to be fetched.
+func (f PostFields) hasAuthors() bool {
+	return f.Has("author") || f.Has("co_authors")
+}
+
+// IsRendered reports whether the content of the post needs to be rendered.
+func (f PostFields) IsRendered() bool {
+	return f.Has("word_count") || f.Has("reading_time") || f.Has("content_html") || f.Has("toc")
+}
+
+// selects reports whether the column of the field needs to be selected.
+// Rendering needs the content, and its modification time to cache the result.
+func (f PostFields) selects(field string) bool {
+	if field == "content" || field == "modified_at" {
+		return f.Has(field) || f.IsRendered()
+	}
+	return f.Has(field)
+}
+
+// optionalPostColumns are the columns of posts that are only selected when
+// their field is. The rest are cheap enough to always select.
+var optionalPostColumns = []struct {
+	field string
+	dest  func(post *Post) interface{}
+}{
+	{"title", func(post *Post) interface{} { return &post.Title }},
+	{"excerpt", func(post *Post) interface{} { return &post.Excerpt }},
+	{"content", func(post *Post) interface{} { return &post.Content }},
+	{"modified_at", func(post *Post) interface{} { return &post.ModifiedAt }},
+	{"version", func(post *Post) interface{} { return &post.Version }},
+}
+
+// postColumns returns the columns to select for fields, in the order scanPost
+// reads them. The query must join posts_publication and posts_cover_url.
+func postColumns(fields PostFields) string {
+	columns := []string{"posts.slug"}
+	for _, c := range optionalPostColumns {
+		if fields.selects(c.field) {
+			columns = append(columns, "posts."+c.field)
+		}
+	}
+	columns = append(columns, "posts_publication.published_at", "posts_cover_url.cover_url", "posts_cover_url.derivatives")
+	return strings.Join(columns, ", ")
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
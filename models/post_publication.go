@@ -0,0 +1,55 @@
+package models
+
+// Unannounced returns up to limit posts that went live at or before now but
+// haven't been announced yet, oldest first.
+func (m PostModel) Unannounced(now string, limit int) ([]*Post, error) {
+	fields := AllPostFields
+
+	rows, err := m.DB.Query(`
+		SELECT `+postColumns(fields)+`
+		FROM posts
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		WHERE posts_publication.announced_at IS NULL
+			AND posts_publication.published_at <= ?
+			AND `+notTrashed+`
+		ORDER BY posts_publication.published_at ASC, posts.slug ASC
+		LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*Post{}
+	for rows.Next() {
+		post, err := scanPost(rows, fields)
+		if err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.FillAuthorsOfPosts(posts); err != nil {
+		return nil, err
+	}
+
+	if err := m.FillTagsOfPosts(posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// MarkAnnounced records that the post has been announced at the given time.
+func (m PostModel) MarkAnnounced(slug string, at string) error {
+	_, err := m.DB.Exec(`
+		UPDATE posts_publication
+		SET announced_at = ?
+		WHERE post_slug = ?`, at, slug)
+	return err
+}
@@ -0,0 +1,55 @@
+package models
+
+import "database/sql"
+
+// Redirect returns the current slug of the post that used to have oldSlug.
+func (m PostModel) Redirect(oldSlug string) (string, error) {
+	var slug string
+
+	err := m.DB.QueryRow(`
+		SELECT post_slug
+		FROM posts_redirects
+		WHERE old_slug = ?`, oldSlug).Scan(&slug)
+	if err != nil {
+		return "", notFound(err, "post %s not found", oldSlug)
+	}
+
+	return slug, nil
+}
+
+// renamePost changes the slug of the post within tx, and redirects the old
+// slug, along with the slugs that already redirected to it, to the new one.
+func renamePost(tx *sql.Tx, oldSlug string, newSlug string, now string) error {
+	_, err := tx.Exec(`UPDATE posts SET slug = ? WHERE slug = ?`, newSlug, oldSlug)
+	if err != nil {
+		return slugTaken(tx, err, newSlug)
+	}
+
+	for _, table := range []string{
+		"posts_publication",
+		"posts_cover_url",
+		"posts_authors",
+		"posts_tags",
+		"posts_revisions",
+		"posts_trash",
+		"posts_media",
+		"posts_redirects",
+	} {
+		_, err := tx.Exec(`UPDATE `+table+` SET post_slug = ? WHERE post_slug = ?`, newSlug, oldSlug)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The post takes its former slug back.
+	_, err = tx.Exec(`DELETE FROM posts_redirects WHERE old_slug = ?`, newSlug)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO posts_redirects
+		(old_slug, post_slug, created_at)
+		VALUES (?, ?, ?)`, oldSlug, newSlug, now)
+	return err
+}
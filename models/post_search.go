@@ -0,0 +1,86 @@
+package models
+
+// PostSearchResult is a post matching a search, along with its relevance.
+type PostSearchResult struct {
+	Post  *Post
+	Score float64
+}
+
+// Search returns a page of the live published posts matching query in their title,
+// excerpt or content, most relevant first, along with the total number of
+// matching posts. The content is always selected so that callers can show
+// where the post matches.
+func (m PostModel) Search(query string, opts PageOptions, fields PostFields) ([]*PostSearchResult, int, error) {
+	fields = fields.With("content")
+	now := CurrentTime()
+
+	var total int
+	err := m.DB.QueryRow(`
+		SELECT COUNT(*)
+		FROM posts
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		WHERE MATCH (posts.title, posts.excerpt, posts.content) AGAINST (? IN NATURAL LANGUAGE MODE)
+			AND posts_publication.published_at <= ?
+			AND `+notTrashed+``,
+		query, now).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT `+postColumns(fields)+`,
+			MATCH (posts.title, posts.excerpt, posts.content) AGAINST (? IN NATURAL LANGUAGE MODE) AS score
+		FROM posts
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		WHERE MATCH (posts.title, posts.excerpt, posts.content) AGAINST (? IN NATURAL LANGUAGE MODE)
+			AND posts_publication.published_at <= ?
+			AND `+notTrashed+`
+		ORDER BY score DESC, posts.slug ASC
+		LIMIT ? OFFSET ?`, query, query, now, opts.PageSize, opts.Offset())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	results := []*PostSearchResult{}
+	posts := []*Post{}
+	for rows.Next() {
+		var score float64
+		post, err := scanPost(extraScanner{rows, []interface{}{&score}}, fields)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		results = append(results, &PostSearchResult{Post: post, Score: score})
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	if fields.hasAuthors() {
+		if err := m.FillAuthorsOfPosts(posts); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if fields.Has("tags") {
+		if err := m.FillTagsOfPosts(posts); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return results, total, nil
+}
+
+// extraScanner scans extra columns that follow the post columns into extra.
+type extraScanner struct {
+	row   scanner
+	extra []interface{}
+}
+
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+func TestSetCoverReturnsReplacedCover(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	linked := "https://example.com/cover.png"
+	if err := posts.Add(&Post{Slug: "post", Title: "Post", Author: author, CoverUrl: &linked}); err != nil {
+		t.Fatal(err)
+	}
+
+	cover, err := posts.SetCover("post", "/uploads/covers/first.png", "covers/first.png", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover != nil {
+		t.Errorf("got cover %+v replacing a linked cover, want none", cover)
+	}
+
+	derivatives := &ImageDerivatives{Variants: []ImageVariant{{Width: 480, Height: 270, Url: "/uploads/covers/second-480w.jpg"}}}
+	if _, err := posts.SetCover("post", "/uploads/covers/second.png", "covers/second.png", derivatives); err != nil {
+		t.Fatal(err)
+	}
+	cover, err = posts.SetCover("post", "/uploads/covers/third.png", "covers/third.png", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover == nil || cover.Key != "covers/second.png" || cover.Derivatives == nil || len(cover.Derivatives.Variants) != 1 {
+		t.Errorf("got cover %+v, want covers/second.png with its variant", cover)
+	}
+}
+
+func TestUpdateReturnsReplacedCover(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Add(&Post{Slug: "post", Title: "Post", Author: author}); err != nil {
+		t.Fatal(err)
+	}
+	uploaded := "/uploads/covers/post.png"
+	if _, err := posts.SetCover("post", uploaded, "covers/post.png", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(slug string, coverUrl *string) *UploadedImage {
+		t.Helper()
+		cover, err := posts.Update("post", &Post{Slug: slug, Title: "Post", Author: author, CoverUrl: coverUrl}, author.UserId, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cover
+	}
+
+	// Keeping the cover, even when renaming the post, keeps its image.
+	if cover := update("post", &uploaded); cover != nil {
+		t.Errorf("got cover %+v keeping the cover, want none", cover)
+	}
+	if cover := update("renamed", &uploaded); cover != nil {
+		t.Errorf("got cover %+v renaming the post, want none", cover)
+	}
+	if cover := update("post", nil); cover == nil || cover.Key != "covers/post.png" {
+		t.Errorf("got cover %+v clearing the cover, want covers/post.png", cover)
+	}
+
+	if _, err := posts.SetCover("post", uploaded, "covers/post.png", nil); err != nil {
+		t.Fatal(err)
+	}
+	linked := "https://example.com/cover.png"
+	if cover := update("post", &linked); cover == nil || cover.Key != "covers/post.png" {
+		t.Errorf("got cover %+v replacing the cover, want covers/post.png", cover)
+	}
+	if cover := update("post", nil); cover != nil {
+		t.Errorf("got cover %+v clearing a linked cover, want none", cover)
+	}
+}
@@ -0,0 +1,220 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// TrashedPost is a post in the trash. Trashed posts are hidden from every
+// other query until they are restored or purged.
+type TrashedPost struct {
+	Post      *Post
+	TrashedAt string
+	TrashedBy string
+}
+
+// Trash moves the post to the trash on behalf of the given user.
+func (m PostModel) Trash(slug string, userId string) error {
+	res, err := m.DB.Exec(`
+		INSERT INTO posts_trash
+		(post_slug, trashed_at, trashed_by)
+		SELECT slug, ?, ?
+		FROM posts
+		WHERE slug = ? AND `+notTrashed, CurrentTime(), userId, slug)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return NotFound("post %s not found", slug)
+	}
+
+	return nil
+}
+
+// Restore takes the post out of the trash.
+func (m PostModel) Restore(slug string) error {
+	res, err := m.DB.Exec(`DELETE FROM posts_trash WHERE post_slug = ?`, slug)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return NotFound("post %s not found in the trash", slug)
+	}
+
+	return nil
+}
+
+// Trashed returns the trashed posts, most recently trashed first. Unless
+// viewerIsAdmin, only the posts that viewerUserId is an author of are
+// returned.
+func (m PostModel) Trashed(viewerUserId string, viewerIsAdmin bool) ([]*TrashedPost, error) {
+	fields := PostSummaryFields
+
+	var conditions []string
+	var args []interface{}
+	if !viewerIsAdmin {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM posts_authors
+			WHERE posts_authors.post_slug = posts.slug AND posts_authors.author_user_id = ?
+		)`)
+		args = append(args, viewerUserId)
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := m.DB.Query(`
+		SELECT `+postColumns(fields)+`, posts_trash.trashed_at, posts_trash.trashed_by
+		FROM posts
+		INNER JOIN posts_trash ON posts_trash.post_slug = posts.slug
+		LEFT JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		`+where+`
+		ORDER BY posts_trash.trashed_at DESC, posts.slug ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trashed := []*TrashedPost{}
+	posts := []*Post{}
+	for rows.Next() {
+		var trashedPost TrashedPost
+		post, err := scanPost(extraScanner{rows, []interface{}{&trashedPost.TrashedAt, &trashedPost.TrashedBy}}, fields)
+		if err != nil {
+			return nil, err
+		}
+		trashedPost.Post = post
+
+		trashed = append(trashed, &trashedPost)
+		posts = append(posts, post)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := m.FillAuthorsOfPosts(posts); err != nil {
+		return nil, err
+	}
+	if err := m.FillTagsOfPosts(posts); err != nil {
+		return nil, err
+	}
+
+	return trashed, nil
+}
+
+// GetTrashed returns the post if it is in the trash.
+func (m PostModel) GetTrashed(slug string) (*TrashedPost, error) {
+	fields := PostSummaryFields
+	var trashedPost TrashedPost
+
+	row := m.DB.QueryRow(`
+		SELECT `+postColumns(fields)+`, posts_trash.trashed_at, posts_trash.trashed_by
+		FROM posts
+		INNER JOIN posts_trash ON posts_trash.post_slug = posts.slug
+		LEFT JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		LEFT JOIN posts_cover_url ON posts_cover_url.post_slug = posts.slug
+		WHERE posts.slug = ?`, slug)
+	post, err := scanPost(extraScanner{row, []interface{}{&trashedPost.TrashedAt, &trashedPost.TrashedBy}}, fields)
+	if err != nil {
+		return nil, notFound(err, "post %s not found in the trash", slug)
+	}
+	trashedPost.Post = post
+
+	if err := m.fill(post, fields); err != nil {
+		return nil, err
+	}
+
+	return &trashedPost, nil
+}
+
+// DeleteTrashed permanently deletes the post, like Delete, as long as it is
+// still in the trash.
+func (m PostModel) DeleteTrashed(slug string) (*UploadedImage, error) {
+	return m.delete(slug, true)
+}
+
+// PurgedPost is a post deleted permanently, with its uploaded cover, if it had
+// one, left for the caller to delete from the blob storage.
+type PurgedPost struct {
+	Slug  string
+	Cover *UploadedImage
+}
+
+// PurgeTrashedBefore permanently deletes the posts trashed before t, and
+// returns the purged posts. The posts restored while purging are skipped.
+func (m PostModel) PurgeTrashedBefore(t string) ([]PurgedPost, error) {
+	rows, err := m.DB.Query(`
+		SELECT post_slug
+		FROM posts_trash
+		WHERE trashed_at < ?`, t)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slugs := []string{}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	purged := []PurgedPost{}
+	for _, slug := range slugs {
+		cover, err := m.DeleteTrashed(slug)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged = append(purged, PurgedPost{Slug: slug, Cover: cover})
+	}
+
+	return purged, nil
+}
+
+// slugTaken describes the duplicate key error err of adding or renaming a post
+// to slug within tx. A slug held by a trashed post is a Conflict rather than a
+// Duplicate, since that post can't be seen until it is restored.
+func slugTaken(tx *sql.Tx, err error, slug string) error {
+	err = duplicate(err, "post %s already exists", slug)
+	if !errors.Is(err, ErrDuplicate) {
+		return err
+	}
+
+	var trashed bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM posts_trash WHERE post_slug = ?)`, slug).Scan(&trashed); err != nil {
+		return err
+	}
+	if trashed {
+		return &Error{
+			Kind:    ErrConflict,
+			Message: fmt.Sprintf("post %s is in the trash; restore or delete it to reuse its slug", slug),
+			Err:     err,
+		}
+	}
+	return err
+}
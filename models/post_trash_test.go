@@ -0,0 +1,110 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAddingTrashedSlugIsAConflict(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	for _, slug := range []string{"live", "trashed"} {
+		if err := posts.Add(&Post{Slug: slug, Title: slug, Author: author}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := posts.Trash("trashed", author.UserId); err != nil {
+		t.Fatal(err)
+	}
+
+	err := posts.Add(&Post{Slug: "live", Title: "Again", Author: author})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("adding a live slug: got %v, want a duplicate", err)
+	}
+	err = posts.Add(&Post{Slug: "trashed", Title: "Again", Author: author})
+	if !errors.Is(err, ErrConflict) {
+		t.Errorf("adding a trashed slug: got %v, want a conflict", err)
+	}
+}
+
+func TestDeleteReturnsUploadedCover(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	linked := "https://example.com/cover.png"
+	for _, post := range []*Post{
+		{Slug: "uploaded"},
+		{Slug: "linked", CoverUrl: &linked},
+	} {
+		post.Title = post.Slug
+		post.Author = author
+		if err := posts.Add(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	derivatives := &ImageDerivatives{Variants: []ImageVariant{{Width: 480, Height: 270, Url: "/uploads/covers/uploaded-480w.jpg"}}}
+	if _, err := posts.SetCover("uploaded", "/uploads/covers/uploaded.png", "covers/uploaded.png", derivatives); err != nil {
+		t.Fatal(err)
+	}
+
+	cover, err := posts.Delete("uploaded")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover == nil || cover.Key != "covers/uploaded.png" || cover.Derivatives == nil || len(cover.Derivatives.Variants) != 1 {
+		t.Errorf("got cover %+v, want covers/uploaded.png with its variant", cover)
+	}
+
+	cover, err = posts.Delete("linked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cover != nil {
+		t.Errorf("got cover %+v for a linked cover, want none", cover)
+	}
+}
+
+func TestDeleteTrashedOnlyDeletesTrashedPosts(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Add(&Post{Slug: "post", Title: "Post", Author: author}); err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Trash("post", author.UserId); err != nil {
+		t.Fatal(err)
+	}
+	if err := posts.Restore("post"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := posts.DeleteTrashed("post"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a restored post: got %v, want not found", err)
+	}
+	if purged, err := posts.PurgeTrashedBefore("9999-12-31 00:00:00"); err != nil || len(purged) != 0 {
+		t.Errorf("got %v purged and %v, want none", purged, err)
+	}
+	if _, err := posts.Get("post"); err != nil {
+		t.Errorf("getting the restored post: %v", err)
+	}
+
+	if err := posts.Trash("post", author.UserId); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := posts.DeleteTrashed("post"); err != nil {
+		t.Errorf("deleting a trashed post: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Revision is the title, excerpt and content of a post right after an edit.
+// Revisions are never modified nor deleted while the post exists.
+type Revision struct {
+	Id           int64  `json:"id"`
+	PostSlug     string `json:"post_slug"`
+	Title        string `json:"title"`
+	Excerpt      string `json:"excerpt"`
+	Content      string `json:"content,omitempty"`
+	EditorUserId string `json:"editor_user_id"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type RevisionModel struct {
+	DB *sql.DB
+}
+
+// AllOfPost returns the revisions of a post, newest first, without their
+// content.
+func (m RevisionModel) AllOfPost(postSlug string) ([]*Revision, error) {
+	rows, err := m.DB.Query(`
+		SELECT id, title, excerpt, editor_user_id, created_at
+		FROM posts_revisions
+		WHERE post_slug = ?
+		ORDER BY id DESC`, postSlug)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+	for rows.Next() {
+		revision := Revision{PostSlug: postSlug}
+
+		err := rows.Scan(&revision.Id, &revision.Title, &revision.Excerpt, &revision.EditorUserId, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, &revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m RevisionModel) Get(postSlug string, id int64) (*Revision, error) {
+	revision := Revision{Id: id, PostSlug: postSlug}
+
+	err := m.DB.QueryRow(`
+		SELECT title, excerpt, content, editor_user_id, created_at
+		FROM posts_revisions
+		WHERE post_slug = ? AND id = ?`, postSlug, id).Scan(
+		&revision.Title, &revision.Excerpt, &revision.Content, &revision.EditorUserId, &revision.CreatedAt)
+	if err != nil {
+		return nil, notFound(err, "revision %d of post %s not found", id, postSlug)
+	}
+
+	return &revision, nil
+}
+
+// Previous returns the revision of the post right before the given one.
+func (m RevisionModel) Previous(postSlug string, id int64) (*Revision, error) {
+	var previousId int64
+
+	err := m.DB.QueryRow(`
+		SELECT id
+		FROM posts_revisions
+		WHERE post_slug = ? AND id < ?
+		ORDER BY id DESC
+		LIMIT 1`, postSlug, id).Scan(&previousId)
+	if err != nil {
+		return nil, notFound(err, "revision %d of post %s is the first", id, postSlug)
+	}
+
+	return m.Get(postSlug, previousId)
+}
+
+// addRevision records the current title, excerpt and content of post within
+// tx.
+func addRevision(tx *sql.Tx, post *Post, editorUserId string, createdAt string) error {
+	_, err := tx.Exec(`
+		INSERT INTO posts_revisions
+		(post_slug, title, excerpt, content, editor_user_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		post.Slug, post.Title, post.Excerpt, post.Content, editorUserId, createdAt)
+	return err
+}
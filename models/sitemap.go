@@ -0,0 +1,71 @@
+package models
+
+import "database/sql"
+
+// Kinds of pages of the sitemap.
+const (
+	SitemapPost   = "post"
+	SitemapPage   = "page"
+	SitemapAuthor = "author"
+)
+
+// SitemapEntry is a public page of the website. Key is the slug of posts and
+// pages and the user id of authors.
+type SitemapEntry struct {
+	Kind       string
+	Key        string
+	ModifiedAt string
+}
+
+type SitemapModel struct {
+	DB *sql.DB
+}
+
+// Entries returns the live posts and pages, and the authors of live posts,
+// in that order. Authors were last modified when their latest post was.
+// Entries are in a stable order, so that sitemaps split in several files list
+// the same pages in each file from one request to the next.
+func (m SitemapModel) Entries() ([]*SitemapEntry, error) {
+	now := CurrentTime()
+
+	rows, err := m.DB.Query(`
+		SELECT 1 AS position, posts.slug AS entry_key, posts.modified_at AS modified_at
+		FROM posts
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		WHERE posts_publication.published_at <= ? AND `+notTrashed+`
+		UNION ALL
+		SELECT 2, pages.slug, pages.modified_at
+		FROM pages
+		WHERE pages.published_at <= ?
+		UNION ALL
+		SELECT 3, posts_authors.author_user_id, MAX(posts.modified_at)
+		FROM posts_authors
+		INNER JOIN posts ON posts.slug = posts_authors.post_slug
+		INNER JOIN posts_publication ON posts_publication.post_slug = posts.slug
+		WHERE posts_publication.published_at <= ? AND `+notTrashed+`
+		GROUP BY posts_authors.author_user_id
+		ORDER BY position ASC, entry_key ASC`, now, now, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	kinds := map[int]string{1: SitemapPost, 2: SitemapPage, 3: SitemapAuthor}
+	entries := []*SitemapEntry{}
+	for rows.Next() {
+		var position int
+		var entry SitemapEntry
+		if err := rows.Scan(&position, &entry.Key, &entry.ModifiedAt); err != nil {
+			return nil, err
+		}
+		entry.Kind = kinds[position]
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,167 @@
+package models
+
+import "database/sql"
+
+type Tag struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	PostCount int    `json:"post_count"`
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+// liveTaggings joins the posts_tags of live posts to tags, so that post counts
+// don't give drafts, scheduled and trashed posts away. It takes the current
+// time as argument.
+const liveTaggings = `LEFT JOIN (posts_tags
+			INNER JOIN posts_publication ON posts_publication.post_slug = posts_tags.post_slug
+				AND posts_publication.published_at <= ?)
+		ON posts_tags.tag_slug = tags.slug
+			AND posts_tags.post_slug NOT IN (SELECT post_slug FROM posts_trash)`
+
+// All returns every tag along with the number of live posts tagged with it.
+func (m TagModel) All() ([]*Tag, error) {
+	rows, err := m.DB.Query(`
+		SELECT tags.slug, tags.name, COUNT(posts_tags.post_slug)
+		FROM tags
+		`+liveTaggings+`
+		GROUP BY tags.slug, tags.name
+		ORDER BY tags.slug`, CurrentTime())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+
+		err := rows.Scan(&tag.Slug, &tag.Name, &tag.PostCount)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func (m TagModel) Get(slug string) (*Tag, error) {
+	var tag Tag = Tag{Slug: slug}
+
+	err := m.DB.QueryRow(`
+		SELECT tags.name, COUNT(posts_tags.post_slug)
+		FROM tags
+		`+liveTaggings+`
+		WHERE tags.slug = ?
+		GROUP BY tags.slug, tags.name`, CurrentTime(), slug).Scan(&tag.Name, &tag.PostCount)
+	if err != nil {
+		return nil, notFound(err, "tag %s not found", slug)
+	}
+
+	return &tag, nil
+}
+
+func (m TagModel) Add(tag *Tag) error {
+	_, err := m.DB.Exec(`
+		INSERT INTO tags
+		(slug, name)
+		VALUES (?, ?)`, tag.Slug, tag.Name)
+	if err != nil {
+		return duplicate(err, "tag %s already exists", tag.Slug)
+	}
+
+	return nil
+}
+
+func (m TagModel) Update(newTag *Tag) error {
+	_, err := m.DB.Exec(`
+		UPDATE tags
+		SET name=?
+		WHERE slug=?`, newTag.Name, newTag.Slug)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete deletes the tag and untags every post tagged with it.
+func (m TagModel) Delete(slug string) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM tags WHERE slug = ?`, slug)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return NotFound("tag %s not found", slug)
+	}
+
+	_, err = tx.Exec(`DELETE FROM posts_tags WHERE tag_slug = ?`, slug)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// setPostTags replaces the tags of a post within tx. Tags that don't exist yet
+// are created, named after their slug.
+func setPostTags(tx *sql.Tx, postSlug string, tagSlugs []string) error {
+	_, err := tx.Exec(`DELETE FROM posts_tags WHERE post_slug = ?`, postSlug)
+	if err != nil {
+		return err
+	}
+	if len(tagSlugs) == 0 {
+		return nil
+	}
+
+	addTagStmt, err := tx.Prepare(`
+		INSERT IGNORE INTO tags
+		(slug, name)
+		VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer addTagStmt.Close()
+
+	tagPostStmt, err := tx.Prepare(`
+		INSERT IGNORE INTO posts_tags
+		(post_slug, tag_slug)
+		VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer tagPostStmt.Close()
+
+	for _, tagSlug := range tagSlugs {
+		if _, err := addTagStmt.Exec(tagSlug, tagSlug); err != nil {
+			return err
+		}
+		if _, err := tagPostStmt.Exec(postSlug, tagSlug); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
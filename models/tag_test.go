@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"hxann.com/blog/constants"
+)
+
+func TestTagPostCountOnlyCountsLivePosts(t *testing.T) {
+	db := testDB(t)
+	posts := PostModel{DB: db}
+	tags := TagModel{DB: db}
+
+	author := &Author{UserId: "author", FullName: "Author", Email: "author@example.com"}
+	if err := (AuthorModel{DB: db}).Add(author); err != nil {
+		t.Fatal(err)
+	}
+
+	tomorrow := time.Now().Add(24 * time.Hour).Format(constants.PublishedAtFormat)
+	for _, post := range []*Post{
+		{Slug: "live", Published: true},
+		{Slug: "draft"},
+		{Slug: "scheduled", Published: true, PublishedAt: tomorrow},
+		{Slug: "trashed", Published: true},
+	} {
+		post.Title = post.Slug
+		post.Author = author
+		post.Tags = []string{"go"}
+		if err := posts.Add(post); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := posts.Trash("trashed", author.UserId); err != nil {
+		t.Fatal(err)
+	}
+
+	tag, err := tags.Get("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag.PostCount != 1 {
+		t.Errorf("Get: post_count = %d, want 1", tag.PostCount)
+	}
+
+	all, err := tags.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all[0].PostCount != 1 {
+		t.Errorf("All: got %+v, want go with a post_count of 1", all)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"hxann.com/blog/constants"
@@ -9,3 +10,11 @@ import (
 func CurrentTime() string {
 	return time.Now().Format(constants.PublishedAtFormat)
 }
+
+// placeholders returns n comma-separated placeholders for an IN clause.
+func placeholders(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strings.Repeat("?, ", n-1) + "?"
+}
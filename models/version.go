@@ -0,0 +1,24 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// ErrStaleVersion is returned when updating a resource that was modified
+// since the version the update was based on.
+var ErrStaleVersion = errors.New("the resource was modified in the meantime")
+
+// lockVersion locks the row that query selects the version of until the end
+// of tx, and returns its version. Unless expected is 0, the version must be
+// expected.
+func lockVersion(tx *sql.Tx, expected int, query string, args ...interface{}) (int, error) {
+	var version int
+	if err := tx.QueryRow(query+" FOR UPDATE", args...).Scan(&version); err != nil {
+		return 0, err
+	}
+	if expected != 0 && version != expected {
+		return 0, ErrStaleVersion
+	}
+	return version, nil
+}
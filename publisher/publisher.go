@@ -0,0 +1,93 @@
+// Package publisher announces scheduled posts once they go live. It runs in
+// the background of the server process, and uses a Redis lock so that only one
+// process announces posts at a time when the app is scaled out.
+package publisher
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"hxann.com/blog/models"
+	"hxann.com/blog/redislock"
+)
+
+const (
+	lockKey = "publisher:lock"
+	// batchSize is the maximum number of posts announced per tick.
+	batchSize = 50
+)
+
+// Hook is called once a post goes live. Hooks should return quickly, as they
+// are run while holding the lock.
+type Hook func(ctx context.Context, post *models.Post) error
+
+type Publisher struct {
+	Sugar       *zap.SugaredLogger
+	Posts       *models.PostModel
+	RedisClient *redis.Client
+	// Interval is how often due posts are looked for.
+	Interval time.Duration
+	Hooks    []Hook
+}
+
+// Run announces due posts every Interval until ctx is done.
+func (p *Publisher) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Publisher) tick(ctx context.Context) {
+	release, ok, err := redislock.TryLock(ctx, p.RedisClient, lockKey, p.Interval)
+	if err != nil {
+		p.Sugar.Errorf("publisher: failed to acquire lock: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := release(); err != nil {
+			p.Sugar.Errorf("publisher: failed to release lock: %v", err)
+		}
+	}()
+
+	now := models.CurrentTime()
+	posts, err := p.Posts.Unannounced(now, batchSize)
+	if err != nil {
+		p.Sugar.Errorf("publisher: failed to fetch due posts: %v", err)
+		return
+	}
+
+	for _, post := range posts {
+		for _, hook := range p.Hooks {
+			if err := hook(ctx, post); err != nil {
+				p.Sugar.Errorw("publisher: hook failed", "slug", post.Slug, "error", err)
+			}
+		}
+
+		if err := p.Posts.MarkAnnounced(post.Slug, now); err != nil {
+			p.Sugar.Errorw("publisher: failed to mark post as announced", "slug", post.Slug, "error", err)
+			return
+		}
+	}
+}
+
+// LogHook logs every post that goes live.
+func LogHook(sugar *zap.SugaredLogger) Hook {
+	return func(ctx context.Context, post *models.Post) error {
+		sugar.Infow("post published", "slug", post.Slug, "published_at", post.PublishedAt)
+		return nil
+	}
+}
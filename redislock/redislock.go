@@ -0,0 +1,36 @@
+// Package redislock implements a simple lock on a Redis key, used to run
+// background jobs on only one process when the app is scaled out.
+package redislock
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// releaseScript deletes the lock only if we still own it.
+var releaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+// TryLock attempts to take the lock of key. If it's taken, release must be
+// called once done. The lock expires on its own after ttl in case the process
+// dies while holding it.
+func TryLock(ctx context.Context, client *redis.Client, key string, ttl time.Duration) (release func() error, ok bool, err error) {
+	token := uuid.New().String()
+
+	ok, err = client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	release = func() error {
+		return releaseScript.Run(ctx, client, []string{key}, token).Err()
+	}
+	return release, true, nil
+}
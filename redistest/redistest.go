@@ -0,0 +1,281 @@
+// Package redistest runs an in-memory Redis server for tests. It speaks just
+// enough of the protocol for the commands the app uses: GET, SET with its
+// expiration and NX and XX options, DEL and PING. Keys expire lazily.
+package redistest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Server is an in-memory Redis server.
+type Server struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	// failing makes every command fail, as if Redis was down.
+	failing bool
+}
+
+// NewClient starts a server, stopped when the test ends, and returns it with a
+// client of it.
+func NewClient(tb testing.TB) (*Server, *redis.Client) {
+	tb.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s := &Server{listener: listener, values: map[string]string{}, expires: map[string]time.Time{}}
+	go s.serve()
+	tb.Cleanup(func() { listener.Close() })
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), MaxRetries: -1})
+	tb.Cleanup(func() { client.Close() })
+	return s, client
+}
+
+// SetFailing makes every command fail, or work again.
+func (s *Server) SetFailing(failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+}
+
+// Get returns the value of key, if it is set.
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key)
+}
+
+// Set sets key to value, expiring after ttl unless it is 0.
+func (s *Server) Set(key, value string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	delete(s.expires, key)
+	if ttl > 0 {
+		s.expires[key] = time.Now().Add(ttl)
+	}
+}
+
+// TTL returns the time to live of key, or 0 if it doesn't expire.
+func (s *Server) TTL(key string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expires, ok := s.expires[key]; ok {
+		return time.Until(expires)
+	}
+	return 0
+}
+
+// Expire makes key expire now.
+func (s *Server) Expire(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.del(key)
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.exec(args, w)
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(args []string, w *bufio.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(args) == 0 {
+		writeError(w, "ERR empty command")
+		return
+	}
+	if s.failing {
+		writeError(w, "LOADING Redis is loading the dataset in memory")
+		return
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		w.WriteString("+PONG\r\n")
+
+	case "GET":
+		if len(args) != 2 {
+			writeError(w, "ERR wrong number of arguments for 'get' command")
+			return
+		}
+		value, ok := s.get(args[1])
+		if !ok {
+			w.WriteString("$-1\r\n")
+			return
+		}
+		writeBulk(w, value)
+
+	case "SET":
+		s.set(args, w)
+
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if _, ok := s.get(key); ok {
+				s.del(key)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+
+	default:
+		writeError(w, "ERR unknown command '"+args[0]+"'")
+	}
+}
+
+func (s *Server) set(args []string, w *bufio.Writer) {
+	if len(args) < 3 {
+		writeError(w, "ERR wrong number of arguments for 'set' command")
+		return
+	}
+	key, value := args[1], args[2]
+
+	var ttl time.Duration
+	var nx, xx, keepTTL bool
+	for i := 3; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 == len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			ttl = time.Duration(n) * time.Millisecond
+			if option == "EX" {
+				ttl = time.Duration(n) * time.Second
+			}
+			i++
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	_, exists := s.get(key)
+	if nx && exists || xx && !exists {
+		w.WriteString("$-1\r\n")
+		return
+	}
+	s.values[key] = value
+	switch {
+	case ttl > 0:
+		s.expires[key] = time.Now().Add(ttl)
+	case !keepTTL:
+		delete(s.expires, key)
+	}
+	w.WriteString("+OK\r\n")
+}
+
+func (s *Server) get(key string) (string, bool) {
+	if expires, ok := s.expires[key]; ok && !time.Now().Before(expires) {
+		s.del(key)
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+func (s *Server) del(key string) {
+	delete(s.values, key)
+	delete(s.expires, key)
+}
+
+// readCommand reads a command, an array of bulk strings.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil // inline command
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(line, "$") {
+			return nil, errors.New("redistest: expected a bulk string")
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeError(w *bufio.Writer, message string) {
+	w.WriteString("-" + message + "\r\n")
+}
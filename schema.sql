@@ -3,6 +3,7 @@ CREATE TABLE `authors` (
 	`full_name` varchar(500) NOT NULL,
 	`email` varchar(320) NOT NULL,
 	`bio` varchar(1000) NOT NULL,
+	`version` int NOT NULL DEFAULT 1,
 	PRIMARY KEY (`user_id`)
 ) ENGINE InnoDB,
   CHARSET utf8mb4,
@@ -14,7 +15,9 @@ CREATE TABLE `posts` (
 	`excerpt` varchar(1000) NOT NULL,
 	`content` text NOT NULL DEFAULT (_utf8mb4 ''),
 	`modified_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP(),
-	PRIMARY KEY (`slug`)
+	`version` int NOT NULL DEFAULT 1,
+	PRIMARY KEY (`slug`),
+	FULLTEXT KEY `posts_fulltext` (`title`, `excerpt`, `content`)
 ) ENGINE InnoDB,
   CHARSET utf8mb4,
   COLLATE utf8mb4_0900_ai_ci;
@@ -32,6 +35,8 @@ CREATE TABLE `posts_authors` (
 CREATE TABLE `posts_cover_url` (
 	`post_slug` varchar(255) NOT NULL,
 	`cover_url` varchar(1000) NOT NULL,
+	`cover_key` varchar(500),
+	`derivatives` json,
 	PRIMARY KEY (`post_slug`)
 ) ENGINE InnoDB,
   CHARSET utf8mb4,
@@ -40,7 +45,109 @@ CREATE TABLE `posts_cover_url` (
 CREATE TABLE `posts_publication` (
 	`post_slug` varchar(255) NOT NULL,
 	`published_at` datetime NOT NULL,
-	PRIMARY KEY (`post_slug`)
+	`announced_at` datetime,
+	PRIMARY KEY (`post_slug`),
+	KEY `posts_publication_announced_at` (`announced_at`, `published_at`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `tags` (
+	`slug` varchar(255) NOT NULL,
+	`name` varchar(255) NOT NULL,
+	PRIMARY KEY (`slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_tags` (
+	`post_slug` varchar(255) NOT NULL,
+	`tag_slug` varchar(255) NOT NULL,
+	PRIMARY KEY (`post_slug`, `tag_slug`),
+	KEY `posts_tags_tag_slug` (`tag_slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_revisions` (
+	`id` bigint unsigned NOT NULL AUTO_INCREMENT,
+	`post_slug` varchar(255) NOT NULL,
+	`title` varchar(1000) NOT NULL,
+	`excerpt` varchar(1000) NOT NULL,
+	`content` text NOT NULL,
+	`editor_user_id` varchar(500) NOT NULL,
+	`created_at` datetime NOT NULL,
+	PRIMARY KEY (`id`),
+	KEY `posts_revisions_post_slug` (`post_slug`, `id`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_trash` (
+	`post_slug` varchar(255) NOT NULL,
+	`trashed_at` datetime NOT NULL,
+	`trashed_by` varchar(500) NOT NULL,
+	PRIMARY KEY (`post_slug`),
+	KEY `posts_trash_trashed_at` (`trashed_at`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_redirects` (
+	`old_slug` varchar(255) NOT NULL,
+	`post_slug` varchar(255) NOT NULL,
+	`created_at` datetime NOT NULL,
+	PRIMARY KEY (`old_slug`),
+	KEY `posts_redirects_post_slug` (`post_slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `pages` (
+	`slug` varchar(255) NOT NULL,
+	`title` varchar(1000) NOT NULL,
+	`excerpt` varchar(1000) NOT NULL,
+	`content` text NOT NULL DEFAULT (_utf8mb4 ''),
+	`menu_order` int NOT NULL DEFAULT 0,
+	`published_at` datetime,
+	`modified_at` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP(),
+	PRIMARY KEY (`slug`),
+	KEY `pages_menu_order` (`menu_order`, `slug`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `pages_authors` (
+	`page_slug` varchar(255) NOT NULL,
+	`author_user_id` varchar(500) NOT NULL,
+	`is_original` tinyint(1) NOT NULL,
+	PRIMARY KEY (`page_slug`, `author_user_id`),
+	UNIQUE KEY `pages_authors_UN` (`page_slug`, `is_original`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `media` (
+	`id` bigint unsigned NOT NULL AUTO_INCREMENT,
+	`storage_key` varchar(500) NOT NULL,
+	`url` varchar(1000) NOT NULL,
+	`filename` varchar(255) NOT NULL,
+	`content_type` varchar(255) NOT NULL,
+	`size` bigint NOT NULL,
+	`author_user_id` varchar(500) NOT NULL,
+	`created_at` datetime NOT NULL,
+	`derivatives` json,
+	PRIMARY KEY (`id`),
+	KEY `media_author_user_id` (`author_user_id`, `created_at`)
 ) ENGINE InnoDB,
   CHARSET utf8mb4,
-  COLLATE utf8mb4_0900_ai_ci;
\ No newline at end of file
+  COLLATE utf8mb4_0900_ai_ci;
+
+CREATE TABLE `posts_media` (
+	`post_slug` varchar(255) NOT NULL,
+	`media_id` bigint unsigned NOT NULL,
+	PRIMARY KEY (`post_slug`, `media_id`),
+	KEY `posts_media_media_id` (`media_id`)
+) ENGINE InnoDB,
+  CHARSET utf8mb4,
+  COLLATE utf8mb4_0900_ai_ci;
@@ -0,0 +1,74 @@
+// Package sitemap writes sitemaps and sitemap indexes as described by the
+// sitemaps.org protocol.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+const ContentType = "application/xml; charset=utf-8"
+
+// MaxURLs is the maximum number of URLs of a sitemap, and of sitemaps of an
+// index. Larger sitemaps must be split and listed in an index.
+const MaxURLs = 50000
+
+const namespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// URL is a page of a sitemap, or a sitemap of an index.
+type URL struct {
+	Loc string
+	// LastMod is the time the page was last modified. It is omitted when
+	// zero.
+	LastMod time.Time
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	NS      string   `xml:"xmlns,attr"`
+	URLs    []entry  `xml:"url"`
+}
+
+type index struct {
+	XMLName  xml.Name `xml:"sitemapindex"`
+	NS       string   `xml:"xmlns,attr"`
+	Sitemaps []entry  `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// WriteURLSet writes a sitemap of the pages.
+func WriteURLSet(w io.Writer, urls []URL) error {
+	return writeXML(w, urlSet{NS: namespace, URLs: entries(urls)})
+}
+
+// WriteIndex writes a sitemap index of the sitemaps.
+func WriteIndex(w io.Writer, sitemaps []URL) error {
+	return writeXML(w, index{NS: namespace, Sitemaps: entries(sitemaps)})
+}
+
+func entries(urls []URL) []entry {
+	entries := make([]entry, len(urls))
+	for i, u := range urls {
+		entries[i].Loc = u.Loc
+		if !u.LastMod.IsZero() {
+			entries[i].LastMod = u.LastMod.Format(time.RFC3339)
+		}
+	}
+	return entries
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	if err := xml.NewEncoder(w).Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
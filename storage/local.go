@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores blobs as files under Dir. The server is expected to serve Dir
+// at BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+func (l *Local) Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error) {
+	if err := checkKey(key); err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(l.Dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return "", err
+	}
+
+	// Writing to a temporary file first means a blob is never served half
+	// written.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(l.BaseURL, "/") + "/" + key, nil
+}
+
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(l.Dir, filepath.FromSlash(key)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// Handler serves the blobs under Dir. Directories are not found rather than
+// listed, since the keys of blobs, such as the covers of drafts, are only
+// public once linked to.
+func (l *Local) Handler() http.Handler {
+	return http.FileServer(filesOnly{http.Dir(l.Dir)})
+}
+
+// filesOnly is a file system without directories.
+type filesOnly struct {
+	http.FileSystem
+}
+
+func (fs filesOnly) Open(name string) (http.File, error) {
+	f, err := fs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return f, nil
+}
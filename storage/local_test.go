@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	local := &Local{Dir: t.TempDir(), BaseURL: "/uploads/"}
+	ctx := context.Background()
+
+	url, err := local.Put(ctx, "covers/draft.png", strings.NewReader("cover"), "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "/uploads/covers/draft.png" {
+		t.Errorf("got URL %s, want /uploads/covers/draft.png", url)
+	}
+
+	handler := http.StripPrefix("/uploads/", local.Handler())
+	for _, test := range []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/uploads/covers/draft.png", http.StatusOK, "cover"},
+		{"/uploads/covers/", http.StatusNotFound, ""},
+		{"/uploads/covers", http.StatusNotFound, ""},
+		{"/uploads/", http.StatusNotFound, ""},
+		{"/uploads/covers/missing.png", http.StatusNotFound, ""},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.path, nil))
+		if w.Code != test.status {
+			t.Errorf("GET %s: got status %d, want %d", test.path, w.Code, test.status)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("GET %s: got body %q, want %q", test.path, w.Body.String(), test.body)
+		}
+		if strings.Contains(w.Body.String(), "draft.png") {
+			t.Errorf("GET %s lists the directory", test.path)
+		}
+	}
+
+	if err := local.Delete(ctx, "covers/draft.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := local.Delete(ctx, "covers/draft.png"); err != nil {
+		t.Errorf("deleting a missing blob: %v", err)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/uploads/covers/draft.png", nil))
+	if w.Code != http.StatusNotFound {
+		body, _ := io.ReadAll(w.Body)
+		t.Errorf("got status %d and body %q after deleting, want 404", w.Code, body)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	local := &Local{Dir: t.TempDir(), BaseURL: "/uploads"}
+	for _, key := range []string{"", "/etc/passwd", "../secret", "..", "covers/../../secret", "covers//a.png"} {
+		if _, err := local.Put(context.Background(), key, strings.NewReader(""), "text/plain"); err != ErrInvalidKey {
+			t.Errorf("Put(%q): got %v, want ErrInvalidKey", key, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package storage stores uploaded files, such as post covers, behind a
+// pluggable backend.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage stores blobs under slash-separated keys, e.g. "covers/my-post.png".
+type Storage interface {
+	// Put stores the blob read from body under key, replacing any blob
+	// already stored there, and returns the public URL of the blob.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) (string, error)
+	// Delete deletes the blob stored under key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that could escape the storage root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") || key == ".." {
+		return ErrInvalidKey
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Package trash permanently deletes posts that have been in the trash for
+// longer than the retention period. Like the publisher, it runs in the
+// background of the server process and uses a Redis lock so that only one
+// process purges at a time.
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"hxann.com/blog/constants"
+	"hxann.com/blog/imaging"
+	"hxann.com/blog/models"
+	"hxann.com/blog/redislock"
+	"hxann.com/blog/storage"
+)
+
+const lockKey = "trash:purger:lock"
+
+type Purger struct {
+	Sugar *zap.SugaredLogger
+	Posts *models.PostModel
+	// Blobs holds the uploaded covers, which are deleted with their posts.
+	Blobs       storage.Storage
+	RedisClient *redis.Client
+	// Retention is how long posts stay in the trash before being purged.
+	Retention time.Duration
+	// Interval is how often expired posts are looked for.
+	Interval time.Duration
+}
+
+// Run purges expired posts every Interval until ctx is done.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		p.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) tick(ctx context.Context) {
+	release, ok, err := redislock.TryLock(ctx, p.RedisClient, lockKey, p.Interval)
+	if err != nil {
+		p.Sugar.Errorf("trash: failed to acquire lock: %v", err)
+		return
+	}
+	if !ok {
+		return
+	}
+	defer func() {
+		if err := release(); err != nil {
+			p.Sugar.Errorf("trash: failed to release lock: %v", err)
+		}
+	}()
+
+	before := time.Now().Add(-p.Retention).Format(constants.PublishedAtFormat)
+	purged, err := p.Posts.PurgeTrashedBefore(before)
+	for _, post := range purged {
+		p.Sugar.Infow("trashed post purged", "slug", post.Slug)
+		if post.Cover == nil {
+			continue
+		}
+		if err := imaging.Delete(ctx, p.Blobs, post.Cover.Key, post.Cover.Derivatives); err != nil {
+			p.Sugar.Errorf("trash: failed to delete the cover of %s: %v", post.Slug, err)
+		}
+	}
+	if err != nil {
+		p.Sugar.Errorf("trash: failed to purge posts: %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Package validation checks fields of request bodies against declarative
+// rules, collecting every invalid field rather than stopping at the first.
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Codes of field errors. Clients can rely on them not changing.
+const (
+	CodeRequired = "required"
+	CodeTooLong  = "too_long"
+	CodeInvalid  = "invalid"
+)
+
+// FieldError is a problem with a field. Field is its JSON name, with the
+// index of the element for fields of lists, like tags[2].
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors are the problems with the fields of a request body.
+type Errors []FieldError
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// A Rule checks a value. It returns the code of the problem and a message to
+// follow the name of the field, or an empty code if the value is valid. Rules
+// other than Required accept empty values, which are only invalid if required.
+type Rule func(value string) (code string, message string)
+
+// A Check validates a field, returning its errors.
+type Check func() Errors
+
+// Validate runs checks and returns their errors as Errors, or nil if every
+// field is valid.
+func Validate(checks ...Check) error {
+	var errs Errors
+	for _, check := range checks {
+		errs = append(errs, check()...)
+	}
+	if errs != nil {
+		return errs
+	}
+	return nil
+}
+
+// Field checks value against rules, reporting the first one that fails.
+func Field(name string, value string, rules ...Rule) Check {
+	return func() Errors {
+		for _, rule := range rules {
+			code, message := rule(value)
+			if code != "" {
+				return Errors{{Field: name, Code: code, Message: name + " " + message}}
+			}
+		}
+		return nil
+	}
+}
+
+// Each checks every element of values against rules, like Field.
+func Each(name string, values []string, rules ...Rule) Check {
+	return func() Errors {
+		var errs Errors
+		for i, value := range values {
+			errs = append(errs, Field(fmt.Sprintf("%s[%d]", name, i), value, rules...)()...)
+		}
+		return errs
+	}
+}
+
+// Required rejects empty values.
+func Required(value string) (string, string) {
+	if value == "" {
+		return CodeRequired, "is required"
+	}
+	return "", ""
+}
+
+// RequiredIf is Required if required holds, for fields that are only required
+// in some requests, and accepts any value otherwise.
+func RequiredIf(required bool) Rule {
+	if required {
+		return Required
+	}
+	return func(string) (string, string) { return "", "" }
+}
+
+// MaxLength rejects values of more than n characters, the length of varchar
+// columns.
+func MaxLength(n int) Rule {
+	return func(value string) (string, string) {
+		if utf8.RuneCountInString(value) > n {
+			return CodeTooLong, fmt.Sprintf("must be at most %d characters", n)
+		}
+		return "", ""
+	}
+}
+
+// MaxBytes rejects values of more than n bytes, the length of text columns.
+func MaxBytes(n int) Rule {
+	return func(value string) (string, string) {
+		if len(value) > n {
+			return CodeTooLong, fmt.Sprintf("must be at most %d bytes", n)
+		}
+		return "", ""
+	}
+}
+
+// Match rejects values not matched by re. description completes "must be".
+func Match(re *regexp.Regexp, description string) Rule {
+	return func(value string) (string, string) {
+		if value != "" && !re.MatchString(value) {
+			return CodeInvalid, "must be " + description
+		}
+		return "", ""
+	}
+}
+
+var slugRe = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// reservedSlugs are routed before /posts/{slug}, so posts with them couldn't
+// be fetched.
+var reservedSlugs = map[string]bool{"search": true, "trash": true}
+
+var slugFormat = Match(slugRe, "lowercase letters and digits separated by hyphens")
+
+// Slug rejects values other than lowercase letters and digits separated by
+// single hyphens.
+func Slug(value string) (string, string) {
+	return slugFormat(value)
+}
+
+// PostSlug rejects the values Slug rejects, and the reserved slugs search and
+// trash.
+func PostSlug(value string) (string, string) {
+	if code, message := Slug(value); code != "" {
+		return code, message
+	}
+	if reservedSlugs[value] {
+		return CodeInvalid, "must not be " + value + ", which is reserved"
+	}
+	return "", ""
+}
+
+// Email rejects values that aren't a bare email address, without a display
+// name.
+func Email(value string) (string, string) {
+	if value == "" {
+		return "", ""
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value {
+		return CodeInvalid, "must be an email address"
+	}
+	return "", ""
+}
+
+// URL rejects values that aren't absolute URLs with one of schemes, or paths
+// on the same host like /uploads/cover.png.
+func URL(schemes ...string) Rule {
+	return func(value string) (string, string) {
+		if value == "" {
+			return "", ""
+		}
+		message := "must be a path or a URL with a scheme of " + strings.Join(schemes, " or ")
+		u, err := url.Parse(value)
+		if err != nil {
+			return CodeInvalid, message
+		}
+		if u.Scheme == "" {
+			if u.Host != "" || !strings.HasPrefix(u.Path, "/") {
+				return CodeInvalid, message
+			}
+			return "", ""
+		}
+		for _, scheme := range schemes {
+			if strings.EqualFold(u.Scheme, scheme) && u.Host != "" {
+				return "", ""
+			}
+		}
+		return CodeInvalid, message
+	}
+}
+
+// Time rejects values that aren't times in layout.
+func Time(layout string) Rule {
+	return func(value string) (string, string) {
+		if value == "" {
+			return "", ""
+		}
+		if _, err := time.Parse(layout, value); err != nil {
+			return CodeInvalid, "must be in the format of " + layout
+		}
+		return "", ""
+	}
+}
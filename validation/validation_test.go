@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		value string
+		code  string
+	}{
+		{"required", Required, "x", ""},
+		{"required empty", Required, "", CodeRequired},
+		{"required if", RequiredIf(true), "", CodeRequired},
+		{"not required", RequiredIf(false), "", ""},
+
+		{"max length", MaxLength(3), "abc", ""},
+		{"max length in characters", MaxLength(3), "été", ""},
+		{"too long", MaxLength(3), "abcd", CodeTooLong},
+		{"max bytes", MaxBytes(4), "été", CodeTooLong},
+		{"max bytes fit", MaxBytes(5), "été", ""},
+
+		{"match", Match(regexp.MustCompile(`^\d+$`), "digits"), "12", ""},
+		{"match empty", Match(regexp.MustCompile(`^\d+$`), "digits"), "", ""},
+		{"no match", Match(regexp.MustCompile(`^\d+$`), "digits"), "1a", CodeInvalid},
+
+		{"slug", Slug, "my-post-2", ""},
+		{"slug empty", Slug, "", ""},
+		{"slug uppercase", Slug, "My-Post", CodeInvalid},
+		{"slug spaces", Slug, "my post", CodeInvalid},
+		{"slug double hyphen", Slug, "my--post", CodeInvalid},
+		{"slug leading hyphen", Slug, "-post", CodeInvalid},
+		{"slug trailing hyphen", Slug, "post-", CodeInvalid},
+		{"slug slash", Slug, "a/b", CodeInvalid},
+		{"slug search", Slug, "search", ""},
+		{"post slug", PostSlug, "my-post-2", ""},
+		{"post slug uppercase", PostSlug, "My-Post", CodeInvalid},
+		{"post slug search", PostSlug, "search", CodeInvalid},
+		{"post slug trash", PostSlug, "trash", CodeInvalid},
+		{"post slug containing a reserved word", PostSlug, "trash-talk", ""},
+
+		{"email", Email, "ann@example.com", ""},
+		{"email empty", Email, "", ""},
+		{"email with a name", Email, "Ann <ann@example.com>", CodeInvalid},
+		{"not an email", Email, "ann", CodeInvalid},
+
+		{"URL", URL("http", "https"), "https://example.com/a.png", ""},
+		{"URL scheme case", URL("http", "https"), "HTTPS://example.com/a.png", ""},
+		{"path", URL("http", "https"), "/uploads/a.png", ""},
+		{"relative path", URL("http", "https"), "uploads/a.png", CodeInvalid},
+		{"protocol-relative URL", URL("http", "https"), "//example.com/a.png", CodeInvalid},
+		{"other scheme", URL("http", "https"), "javascript:alert(1)", CodeInvalid},
+		{"no host", URL("http", "https"), "https:///a.png", CodeInvalid},
+
+		{"time", Time("2006-01-02 15:04:05"), "2024-05-01 12:00:00", ""},
+		{"time empty", Time("2006-01-02 15:04:05"), "", ""},
+		{"invalid time", Time("2006-01-02 15:04:05"), "2024-05-01T12:00:00Z", CodeInvalid},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, message := test.rule(test.value)
+			if code != test.code {
+				t.Errorf("got code %q for %q, want %q", code, test.value, test.code)
+			}
+			if (code == "") != (message == "") {
+				t.Errorf("got code %q with message %q", code, message)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	err := Validate(
+		Field("slug", "search", Required, MaxLength(255), PostSlug),
+		Field("title", "", Required, MaxLength(3)),
+		Field("excerpt", "abcd", MaxLength(3), Required),
+		Field("content", "ok", Required),
+		Each("tags", []string{"go", "", "toolong"}, Required, MaxLength(3)),
+	)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got %v, want Errors", err)
+	}
+	want := Errors{
+		{Field: "slug", Code: CodeInvalid, Message: "slug must not be search, which is reserved"},
+		{Field: "title", Code: CodeRequired, Message: "title is required"},
+		{Field: "excerpt", Code: CodeTooLong, Message: "excerpt must be at most 3 characters"},
+		{Field: "tags[1]", Code: CodeRequired, Message: "tags[1] is required"},
+		{Field: "tags[2]", Code: CodeTooLong, Message: "tags[2] must be at most 3 characters"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %+v, want %+v", errs, want)
+	}
+	if got := err.Error(); !strings.HasPrefix(got, "slug must not be search, which is reserved; title is required; ") {
+		t.Errorf("got message %q", got)
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	err := Validate(
+		Field("slug", "a-post", Required, Slug),
+		Field("excerpt", "", MaxLength(3)),
+		Each("tags", nil, Required),
+	)
+	if err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+}